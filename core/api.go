@@ -45,17 +45,16 @@ package core
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	_log "log"
 	"os"
 	"sync"
 
 	"github.com/chirino/graphql"
 	"github.com/dosco/graphjin/core/internal/allow"
-	"github.com/dosco/graphjin/core/internal/crypto"
 	"github.com/dosco/graphjin/core/internal/psql"
 	"github.com/dosco/graphjin/core/internal/qcode"
 	"github.com/dosco/graphjin/core/internal/sdata"
@@ -71,8 +70,16 @@ const (
 	// User ID value for authenticated users
 	UserIDKey
 
-	// User role if pre-defined
+	// User role if pre-defined, either a single role (string) or
+	// the set of roles ([]string) held by the user
 	UserRoleKey
+
+	// Role requested by the client for this request, it must be one of
+	// the roles held by the user
+	ActiveRoleKey
+
+	// Claims from the users JWT token (map[string]interface{})
+	UserClaimsKey
 )
 
 // GraphJin struct is an instance of the GraphJin engine it holds all the required information like
@@ -84,7 +91,8 @@ type GraphJin struct {
 	dbinfo      *sdata.DBInfo
 	schema      *sdata.DBSchema
 	allowList   *allow.List
-	encKey      [32]byte
+	encKeys     []encKey
+	maskKey     string
 	queries     map[string]*cquery
 	roles       map[string]*Role
 	roleStmt    string
@@ -93,8 +101,10 @@ type GraphJin struct {
 	abacEnabled bool
 	qc          *qcode.Compiler
 	pc          *psql.Compiler
-	ge          *graphql.Engine
+	ges         sync.Map
 	subs        sync.Map
+	croles      sync.Map
+	crolesLock  sync.Mutex
 }
 
 // NewGraphJin creates the GraphJin struct, this involves querying the database to learn its
@@ -144,13 +154,7 @@ func newGraphJin(conf *Config, db *sql.DB, dbinfo *sdata.DBInfo) (*GraphJin, err
 		return nil, err
 	}
 
-	if conf.SecretKey != "" {
-		sk := sha256.Sum256([]byte(conf.SecretKey))
-		conf.SecretKey = ""
-		gj.encKey = sk
-	} else {
-		gj.encKey = crypto.NewEncryptionKey()
-	}
+	gj.initEncKeys()
 
 	return gj, nil
 }
@@ -162,6 +166,7 @@ type Result struct {
 	name string
 	sql  string
 	role string
+	cost int
 
 	Error      string          `json:"message,omitempty"`
 	Data       json.RawMessage `json:"data,omitempty"`
@@ -213,18 +218,6 @@ func (gj *GraphJin) GraphQLEx(
 		return res, errors.New("mysql: mutations not supported")
 	}
 
-	// use the chirino/graphql library for introspection queries
-	// disabled when allow list is enforced
-	if !gj.conf.EnforceAllowList && ct.name == "IntrospectionQuery" {
-		r := gj.ge.ServeGraphQL(&graphql.Request{Query: query})
-		res.Data = r.Data
-
-		if r.Error() != nil {
-			res.Error = r.Error().Error()
-		}
-		return res, r.Error()
-	}
-
 	var role string
 
 	if keyExists(c, UserIDKey) {
@@ -233,6 +226,12 @@ func (gj *GraphJin) GraphQLEx(
 		role = "anon"
 	}
 
+	// use the chirino/graphql library for introspection queries
+	// disabled when allow list is enforced
+	if !gj.conf.EnforceAllowList && ct.name == "IntrospectionQuery" {
+		return gj.introspect(c, res, query, role)
+	}
+
 	qr, err := ct.execQuery(query, vars, role)
 
 	if err != nil {
@@ -241,6 +240,7 @@ func (gj *GraphJin) GraphQLEx(
 
 	if qr.q != nil {
 		res.sql = qr.q.st.sql
+		res.cost = queryCost(qr.q.st.qc)
 	}
 
 	res.Data = json.RawMessage(qr.data)
@@ -249,6 +249,49 @@ func (gj *GraphJin) GraphQLEx(
 	return res, err
 }
 
+// introspect answers introspection queries with the schema seen
+// by the role of the user
+func (gj *GraphJin) introspect(c context.Context, res *Result, query, role string) (*Result, error) {
+	ur, err := gj.userRole(c, role)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	if ur != "" {
+		role = ur
+	}
+
+	ge, err := gj.introEngine(role)
+	if err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	r := ge.ServeGraphQL(&graphql.Request{Query: query})
+	res.Data = r.Data
+	res.role = role
+
+	if r.Error() != nil {
+		res.Error = r.Error().Error()
+	}
+	return res, r.Error()
+}
+
+// SchemaSDL returns the GraphQL schema seen by the role in the schema
+// definition language (SDL). It's the same schema returned by introspection
+// queries made with the role.
+func (gj *GraphJin) SchemaSDL(role string) (string, error) {
+	if _, ok := gj.getRole(role); !ok {
+		return "", fmt.Errorf("role not defined: %s", role)
+	}
+
+	ge, err := gj.introEngine(role)
+	if err != nil {
+		return "", err
+	}
+	return ge.Schema.String(), nil
+}
+
 // Operation function return the operation type and name from the query.
 // It uses a very fast algorithm to extract the operation without having to parse the query.
 func Operation(query string) (OpType, string) {
@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/dosco/graphjin/core/internal/psql"
+	"github.com/dosco/graphjin/core/internal/sdata"
 	"github.com/dosco/graphjin/internal/jsn"
 )
 
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
 // argList function is used to create a list of arguments to pass
 // to a prepared statement.
 
@@ -65,9 +71,16 @@ func (gj *GraphJin) argList(c context.Context, md psql.Metadata, vars []byte, rc
 				return ar, argErr(p)
 			}
 
+		case psql.MaskKeyParam:
+			vl[i] = gj.maskKey
+
+		case psql.AuditOpParam, psql.AuditHashParam, psql.AuditRoleParam,
+			psql.AuditUserIDParam, psql.AuditVarsParam:
+			// set by auditArgs
+
 		case "cursor":
 			if v, ok := fields["cursor"]; ok && v[0] == '"' {
-				v1, err := gj.decrypt(string(v[1 : len(v)-1]))
+				v1, err := gj.decrypt(string(v[1:len(v)-1]), keyCursor)
 				if err != nil {
 					return ar, err
 				}
@@ -80,13 +93,20 @@ func (gj *GraphJin) argList(c context.Context, md psql.Metadata, vars []byte, rc
 		default:
 			if v, ok := fields[p.Name]; ok {
 				switch {
-				case p.IsArray && v[0] != '[':
+				case p.IsArray && v[0] != '[' && !p.Encrypted:
 					return ar, fmt.Errorf("variable '%s' should be an array of type '%s'", p.Name, p.Type)
 
 				case p.Type == "json" && v[0] != '[' && v[0] != '{':
 					return ar, fmt.Errorf("variable '%s' should be an array or object", p.Name)
 				}
-				vl[i] = parseVarVal(v)
+				if err := gj.validateVar(p, v); err != nil {
+					return ar, err
+				}
+				if p.Encrypted {
+					vl[i] = v
+				} else {
+					vl[i] = parseVarVal(v)
+				}
 
 			} else if rc != nil {
 				if v, ok := rc.Vars[p.Name]; ok {
@@ -99,11 +119,94 @@ func (gj *GraphJin) argList(c context.Context, md psql.Metadata, vars []byte, rc
 				return ar, argErr(p)
 			}
 		}
+
+		if p.Encrypted && vl[i] != nil {
+			if vl[i], err = gj.encryptArg(vl[i]); err != nil {
+				return ar, err
+			}
+		}
+
+		if p.GlobalID && vl[i] != nil {
+			if vl[i], err = gj.decodeGlobalIDs(vl[i]); err != nil {
+				return ar, fmt.Errorf("variable '%s': %w", p.Name, err)
+			}
+		}
 	}
 	ar.values = vl
 	return ar, nil
 }
 
+// validateVar checks the value of a variable is valid for the GraphQL
+// type of its column, it's the same type returned by introspection
+func (gj *GraphJin) validateVar(p psql.Param, v json.RawMessage) error {
+	if p.Type == "" || v[0] == 'n' {
+		return nil
+	}
+
+	if v[0] == '[' {
+		var vals []json.RawMessage
+		if err := json.Unmarshal(v, &vals); err != nil {
+			return err
+		}
+		if !p.IsArray && !strings.HasSuffix(p.Type, "[]") {
+			return nil
+		}
+		for _, v1 := range vals {
+			if err := gj.validateVar(p, v1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t := gqlTypeName(gj.schema, p.Type)
+
+	if !validVarVal(gj.schema, p.Type, t, v) {
+		return fmt.Errorf("variable '%s' should be of type '%s'", p.Name, t)
+	}
+	return nil
+}
+
+func validVarVal(sc *sdata.DBSchema, dbType, t string, v json.RawMessage) bool {
+	var s string
+	isStr := (v[0] == '"')
+
+	if isStr {
+		if err := json.Unmarshal(v, &s); err != nil {
+			return false
+		}
+	} else {
+		s = string(v)
+	}
+
+	switch t {
+	case "Int":
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+
+	case "Float":
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+
+	case "Boolean":
+		return s == "true" || s == "false"
+
+	case "UUID":
+		return isStr && uuidRe.MatchString(s)
+	}
+
+	if e, ok := sc.GetEnum(strings.TrimSuffix(dbType, "[]")); ok {
+		for _, ev := range e.Values {
+			if ev == s {
+				return isStr
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
 func parseVarVal(v json.RawMessage) interface{} {
 	switch v[0] {
 	case '[', '{':
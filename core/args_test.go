@@ -0,0 +1,111 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chirino/graphql/schema"
+	"github.com/dosco/graphjin/core/internal/psql"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func testEnumSchema(t *testing.T) *sdata.DBSchema {
+	di := sdata.GetTestDBInfo()
+	di.Enums = []sdata.DBEnum{
+		{Name: "product_status", Values: []string{"draft", "published"}},
+	}
+
+	sc, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestValidVarVal(t *testing.T) {
+	sc := testEnumSchema(t)
+
+	tests := []struct {
+		dbType string
+		val    string
+		valid  bool
+	}{
+		{"bigint", `5`, true},
+		{"bigint", `"5"`, true},
+		{"bigint", `5.5`, false},
+		{"bigint", `"five"`, false},
+		{"numeric(7,2)", `5.5`, true},
+		{"numeric(7,2)", `"abc"`, false},
+		{"boolean", `true`, true},
+		{"boolean", `"yes"`, false},
+		{"uuid", `"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`, true},
+		{"uuid", `"A0EEBC999C0B4EF8BB6D6BB9BD380A11"`, true},
+		{"uuid", `"a0eebc99-9c0b-4ef8-bb6d"`, false},
+		{"uuid", `"g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`, false},
+		{"uuid", `12345`, false},
+		{"product_status", `"draft"`, true},
+		{"product_status", `"deleted"`, false},
+		{"product_status", `5`, false},
+		{"product_status[]", `"published"`, true},
+		{"text", `"anything"`, true},
+		{"jsonb", `{"a": 1}`, true},
+	}
+
+	for _, v := range tests {
+		t1 := gqlTypeName(sc, v.dbType)
+		if ok := validVarVal(sc, v.dbType, t1, json.RawMessage(v.val)); ok != v.valid {
+			t.Errorf("%s (%s): %s expected valid %t got %t", v.dbType, t1, v.val, v.valid, ok)
+		}
+	}
+}
+
+func TestValidateVar(t *testing.T) {
+	gj := &GraphJin{schema: testEnumSchema(t)}
+
+	tests := []struct {
+		p     psql.Param
+		val   string
+		valid bool
+	}{
+		{psql.Param{Name: "id", Type: "uuid"}, `"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`, true},
+		{psql.Param{Name: "id", Type: "uuid"}, `"not-a-uuid"`, false},
+		{psql.Param{Name: "id", Type: "uuid"}, `null`, true},
+		{psql.Param{Name: "id", Type: ""}, `"not-a-uuid"`, true},
+		{psql.Param{Name: "ids", Type: "uuid", IsArray: true}, `["a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"]`, true},
+		{psql.Param{Name: "ids", Type: "uuid", IsArray: true}, `["a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "bad"]`, false},
+		{psql.Param{Name: "status", Type: "product_status[]"}, `["draft", "published"]`, true},
+		{psql.Param{Name: "status", Type: "product_status[]"}, `["draft", "deleted"]`, false},
+		{psql.Param{Name: "ids", Type: "bigint", IsArray: true}, `[[1, 2], [3]]`, true},
+		{psql.Param{Name: "ids", Type: "bigint", IsArray: true}, `[[1, 2], ["x"]]`, false},
+		// lists for columns that are not arrays are left to the database
+		{psql.Param{Name: "id", Type: "bigint"}, `["x"]`, true},
+	}
+
+	for _, v := range tests {
+		err := gj.validateVar(v.p, json.RawMessage(v.val))
+		if (err == nil) != v.valid {
+			t.Errorf("%s (%s): %s expected valid %t got error %v", v.p.Name, v.p.Type, v.val, v.valid, err)
+		}
+	}
+}
+
+func TestColTypeNotNull(t *testing.T) {
+	sc := testEnumSchema(t)
+	in := introSchema{sc: sc, es: schema.New()}
+
+	tests := []struct {
+		col sdata.DBColumn
+		exp string
+	}{
+		{sdata.DBColumn{Name: "id", Type: "bigint", NotNull: true}, "Int!"},
+		{sdata.DBColumn{Name: "name", Type: "text"}, "String"},
+		{sdata.DBColumn{Name: "tags", Type: "text[]", Array: true, NotNull: true}, "[String]!"},
+		{sdata.DBColumn{Name: "status", Type: "product_status"}, "product_status"},
+	}
+
+	for _, v := range tests {
+		if s := in.colType(v.col).String(); s != v.exp {
+			t.Errorf("%s: expected %s got %s", v.col.Name, v.exp, s)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dosco/graphjin/core/internal/psql"
+)
+
+const redacted = "[REDACTED]"
+
+// auditArgs sets the values of the params used to record
+// the mutation in the audit table
+func (c *scontext) auditArgs(cq *cquery, ar *args, vars []byte, role string) error {
+	for i, p := range cq.st.md.Params() {
+		switch p.Name {
+		case psql.AuditOpParam:
+			ar.values[i] = c.name
+
+		case psql.AuditHashParam:
+			h := sha256.Sum256(cq.q.query)
+			ar.values[i] = hex.EncodeToString(h[:])
+
+		case psql.AuditRoleParam:
+			ar.values[i] = role
+
+		case psql.AuditUserIDParam:
+			if v := c.Value(UserIDKey); v != nil {
+				ar.values[i] = fmt.Sprint(v)
+			}
+
+		case psql.AuditVarsParam:
+			v, err := c.gj.redactVars(vars)
+			if err != nil {
+				return err
+			}
+			ar.values[i] = v
+		}
+	}
+	return nil
+}
+
+// redactVars replaces the values of the variables listed
+// in AuditRedact at any depth
+func (gj *GraphJin) redactVars(vars []byte) (json.RawMessage, error) {
+	if len(vars) == 0 {
+		return json.RawMessage(`{}`), nil
+	}
+
+	if len(gj.conf.AuditRedact) == 0 {
+		return json.RawMessage(vars), nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(vars, &v); err != nil {
+		return nil, err
+	}
+
+	rm := make(map[string]struct{}, len(gj.conf.AuditRedact))
+	for _, k := range gj.conf.AuditRedact {
+		rm[k] = struct{}{}
+	}
+
+	b, err := json.Marshal(redactVal(v, rm))
+	return json.RawMessage(b), err
+}
+
+func redactVal(v interface{}, rm map[string]struct{}) interface{} {
+	switch v1 := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v1 {
+			if _, ok := rm[k]; ok {
+				v1[k] = redacted
+			} else {
+				v1[k] = redactVal(val, rm)
+			}
+		}
+	case []interface{}:
+		for i := range v1 {
+			v1[i] = redactVal(v1[i], rm)
+		}
+	}
+	return v
+}
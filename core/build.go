@@ -26,10 +26,20 @@ func (gj *GraphJin) compileQuery(cq *cquery, role string) error {
 	if gj.allowList != nil && gj.conf.EnforceAllowList {
 		if cq1, ok := gj.queries[(cq.q.name + role)]; ok {
 			cq.q = cq1.q
+		} else if cq1, ok := gj.combinedRoleQuery(cq.q.name, role); ok {
+			// combined roles are not prepared ahead of time so the
+			// saved query is compiled with the combined role config
+			cq.q = cq1.q
 		} else {
 			return errNotFound
 		}
 
+		// with attribute based access control the role is only known once
+		// the query runs so it's checked after
+		if !gj.queryRoleAllowed(cq, role) && !gj.isMultiStmt(cq, role) {
+			return fmt.Errorf("query not allowed for role: %s", role)
+		}
+
 		if cq.st.sql == "" {
 			cq.Do(func() {
 				err = gj.compileQueryFn(cq, role)
@@ -43,12 +53,25 @@ func (gj *GraphJin) compileQuery(cq *cquery, role string) error {
 	return err
 }
 
+// combinedRoleQuery returns the saved query for a combined role or a role
+// found using the roles_query, it's the one saved for any of the roles it
+// combines or for the user role
+func (gj *GraphJin) combinedRoleQuery(name, role string) (*cquery, bool) {
+	// queries are saved for the user role and mutations for each role
+	for _, r := range append(strings.Split(role, ","), "user") {
+		if cq, ok := gj.queries[(name + r)]; ok {
+			return cq, true
+		}
+	}
+	return nil, false
+}
+
 func (gj *GraphJin) compileQueryFn(cq *cquery, role string) error {
 	var err error
 
 	switch cq.q.op {
 	case qcode.QTQuery, qcode.QTSubscription:
-		if gj.abacEnabled && role == "user" {
+		if gj.isMultiStmt(cq, role) {
 			err = gj.buildMultiStmt(cq)
 		} else {
 			err = gj.buildRoleStmt(cq, role)
@@ -69,7 +92,7 @@ func (gj *GraphJin) buildRoleStmt(cq *cquery, role string) error {
 	query := cq.q.query
 	vars := cq.q.vars
 
-	ro, ok := gj.roles[role]
+	ro, ok := gj.getRole(role)
 	if !ok {
 		return fmt.Errorf(`roles '%s' not defined in c.gj.config`, role)
 	}
@@ -127,6 +150,11 @@ func (gj *GraphJin) buildMultiStmt(cq *cquery) error {
 			continue
 		}
 
+		// skip roles the query is not allowed for
+		if !gj.queryRoleAllowed(cq, role.Name) {
+			continue
+		}
+
 		qc, err := gj.qc.Compile(query, vm, role.Name)
 		if err != nil {
 			return err
@@ -142,6 +170,10 @@ func (gj *GraphJin) buildMultiStmt(cq *cquery) error {
 		w.Reset()
 	}
 
+	if len(cq.stmts) == 0 {
+		return errors.New("query not allowed for any role")
+	}
+
 	fsql, err := gj.renderUserQuery(&md, cq.stmts)
 	cq.st = cq.stmts[0]
 	cq.st.md = md
@@ -150,6 +182,29 @@ func (gj *GraphJin) buildMultiStmt(cq *cquery) error {
 	return err
 }
 
+func (gj *GraphJin) isMultiStmt(cq *cquery, role string) bool {
+	return gj.abacEnabled && role == "user" &&
+		(cq.q.op == qcode.QTQuery || cq.q.op == qcode.QTSubscription)
+}
+
+// queryRoleAllowed checks if the role is allowed to run the query, queries
+// in the allow list can be limited to certain roles using a roles directive.
+// For combined roles it's enough if any one role is allowed.
+func (gj *GraphJin) queryRoleAllowed(cq *cquery, role string) bool {
+	if len(cq.q.roles) == 0 {
+		return true
+	}
+
+	for _, r := range strings.Split(role, ",") {
+		for _, v := range cq.q.roles {
+			if r == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //nolint: errcheck
 func (gj *GraphJin) renderUserQuery(md *psql.Metadata, stmts []stmt) (string, error) {
 	if gj.conf.RolesQuery == "" {
@@ -175,26 +230,11 @@ func (gj *GraphJin) renderUserQuery(md *psql.Metadata, stmts []stmt) (string, er
 		w.WriteString(`) `)
 	}
 
-	w.WriteString(`END) as "__root" FROM (SELECT (CASE WHEN EXISTS (`)
-	md.RenderVar(w, gj.conf.RolesQuery)
-	w.WriteString(`) THEN `)
-
-	w.WriteString(`(SELECT (CASE`)
-	for _, s := range stmts {
-		if s.role.Match == "" {
-			continue
-		}
-		w.WriteString(` WHEN `)
-		w.WriteString(s.role.Match)
-		w.WriteString(` THEN '`)
-		w.WriteString(s.role.Name)
-		w.WriteString(`'`)
-	}
-
-	w.WriteString(` ELSE 'user' END) FROM (`)
-	md.RenderVar(w, gj.conf.RolesQuery)
-	w.WriteString(`) AS "_sg_auth_roles_query" LIMIT 1) `)
-	w.WriteString(`ELSE 'anon' END) FROM (VALUES (1)) AS "_sg_auth_filler") AS "_sg_auth_info"(role) LIMIT 1 `)
+	// a user holding more than one role has no statement of its own
+	// so no data is returned and the query is run with the combined role
+	w.WriteString(`END) as "__root" FROM (SELECT `)
+	gj.renderRoleMatch(w, md)
+	w.WriteString(` FROM (VALUES (1)) AS "_sg_auth_filler") AS "_sg_auth_info"(role) LIMIT 1 `)
 
 	return w.String(), nil
 }
@@ -18,6 +18,11 @@ type Config struct {
 	// the cursor. Auto-generated if not set
 	SecretKey string `mapstructure:"secret_key"`
 
+	// SecretKeys is a list of previous secret keys used only to decrypt
+	// values (eg. cursors) encrypted before the SecretKey was rotated. If
+	// SecretKey is not set then the first key in this list is used to encrypt
+	SecretKeys []string `mapstructure:"secret_keys"`
+
 	// DisableAllowList when set to true entirely disables the
 	// allow list workflow and all queries are always compiled
 	// even in production. (Warning possible security concern)
@@ -31,11 +36,36 @@ type Config struct {
 	// path is assumed to be the same as the config path (allow.list)
 	AllowListFile string `mapstructure:"allow_list_file"`
 
-	// SetUserID forces the database session variable `user.id` to
-	// be set to the user id. This variables can be used by triggers
-	// or other database functions
+	// SetUserID forces the database variable `user.id` to be set to the
+	// user id for the duration of the request transaction. This variables
+	// can be used by triggers or other database functions
 	SetUserID bool `mapstructure:"set_user_id"`
 
+	// SetLocalRole runs each request in a transaction that switches to the
+	// database role with the same name as the user role using `SET LOCAL ROLE`.
+	// This allows Postgres row level security policies to enforce access
+	SetLocalRole bool `mapstructure:"set_local_role"`
+
+	// LocalSettings are set with `set_config` for the duration of each request
+	// transaction. Values starting with `$` are taken from the request, these are
+	// $user_id, $user_id_provider, $user_role, $jwt_claims (as json), $jwt.<claim>
+	// or any variable set in ReqConfig. All other values are used as is.
+	LocalSettings map[string]string `mapstructure:"local_settings"`
+
+	// AuditTable when set records every mutation in this table within the
+	// same transaction. The operation name, query hash, role, user id,
+	// variables, tables written to and primary keys of the changed rows are saved
+	AuditTable string `mapstructure:"audit_table"`
+
+	// AuditRedact is a list of variable names whose values are replaced
+	// with '[REDACTED]' in the audit table (eg. password)
+	AuditRedact []string `mapstructure:"audit_redact"`
+
+	// GlobalIDs when set returns the id of tables with a primary key as
+	// an opaque global id made from the table name and the primary key.
+	// Any row can then be fetched by its id using the node and nodes queries
+	GlobalIDs bool `mapstructure:"global_ids"`
+
 	// DefaultBlock ensures that in anonymous mode (role 'anon') all tables
 	// are blocked from queries and mutations. To open access to tables in
 	// anonymous mode they have to be added to the 'anon' role config.
@@ -110,6 +140,20 @@ type Table struct {
 	Type      string
 	Blocklist []string
 	Columns   []Column
+
+	// SoftDelete is a timestamp or boolean column that marks a row as deleted.
+	// Deletes set this column instead of removing the row and queries
+	// exclude the rows marked as deleted
+	SoftDelete string `mapstructure:"soft_delete"`
+
+	// Version is an integer or timestamp column used for optimistic
+	// concurrency. Updates must include the last seen value of this column
+	// and fail with a conflict error if the row was changed since
+	Version string
+
+	// Description is used in the GraphQL schema when the table
+	// has no comment in the database
+	Description string
 }
 
 // Column struct defines a database column
@@ -118,12 +162,28 @@ type Column struct {
 	Type       string
 	Primary    bool
 	ForeignKey string `mapstructure:"related_to"`
+
+	// Encrypted columns have their values encrypted using a key derived
+	// from the SecretKey before they are saved and decrypted when queried
+	Encrypted bool
+
+	// Deterministic encryption allows for equality filters on encrypted
+	// columns but reveals when two values are the same
+	Deterministic bool
+
+	// Description is used in the GraphQL schema when the column
+	// has no comment in the database
+	Description string
 }
 
 // Role struct contains role specific access control values for for all database tables
 type Role struct {
-	Name   string
-	Match  string
+	Name  string
+	Match string
+
+	// Extends is a list of roles whose table configs are inherited by this role
+	Extends []string `mapstructure:"extend"`
+
 	Tables []RoleTable
 	tm     map[string]*RoleTable
 }
@@ -145,7 +205,9 @@ type Query struct {
 	Limit            int
 	Filters          []string
 	Columns          []string
+	Masks            map[string]string
 	DisableFunctions bool `mapstructure:"disable_functions"`
+	WithDeleted      bool `mapstructure:"with_deleted"`
 	Block            bool
 }
 
@@ -153,6 +215,7 @@ type Query struct {
 type Insert struct {
 	Filters []string
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -161,6 +224,7 @@ type Insert struct {
 type Update struct {
 	Filters []string
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -168,6 +232,7 @@ type Update struct {
 type Upsert struct {
 	Filters []string
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -6,7 +6,10 @@ import (
 )
 
 var (
-	errNotFound = errors.New("not found in prepared statements")
+	errNotFound    = errors.New("not found in prepared statements")
+	errCheckFailed = errors.New("mutation check failed")
+	errConflict    = errors.New("update conflict: row was changed")
+	errGlobalID    = errors.New("invalid global id")
 )
 
 func keyExists(ct context.Context, key contextkey) bool {
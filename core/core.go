@@ -3,8 +3,9 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dosco/graphjin/core/internal/psql"
@@ -55,6 +56,12 @@ type scontext struct {
 	name string
 }
 
+// dbConn is either a database connection or a transaction
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type qres struct {
 	q    *cquery
 	data []byte
@@ -67,7 +74,7 @@ func (gj *GraphJin) initSchema() error {
 	// If gj.di is not null then it's probably set
 	// for tests
 	if gj.dbinfo == nil {
-		gj.dbinfo, err = sdata.GetDBInfo(gj.db, gj.conf.DBType, gj.conf.Blocklist)
+		gj.dbinfo, err = sdata.GetDBInfo(gj.db, gj.conf.DBType, gj.conf.DBSchema, gj.conf.Blocklist)
 		if err != nil {
 			return err
 		}
@@ -92,9 +99,14 @@ func (gj *GraphJin) initSchema() error {
 func (gj *GraphJin) initCompilers() error {
 	var err error
 
+	if gj.sessionTx() && gj.schema.Type() == "mysql" {
+		return errors.New("mysql: set_user_id, set_local_role and local_settings not supported")
+	}
+
 	qcc := qcode.Config{
 		DefaultBlock: gj.conf.DefaultBlock,
 		DefaultLimit: gj.conf.DefaultLimit,
+		GlobalIDs:    gj.conf.GlobalIDs,
 	}
 
 	if gj.allowList != nil && gj.conf.EnforceAllowList {
@@ -110,7 +122,10 @@ func (gj *GraphJin) initCompilers() error {
 		return err
 	}
 
-	gj.pc = psql.NewCompiler(psql.Config{Vars: gj.conf.Vars})
+	gj.pc = psql.NewCompiler(psql.Config{
+		Vars:       gj.conf.Vars,
+		AuditTable: gj.conf.AuditTable,
+	})
 	return nil
 }
 
@@ -120,6 +135,17 @@ func (c *scontext) execQuery(query string, vars []byte, role string) (qres, erro
 		return res, err
 	}
 
+	// a user matching more than one role with the roles_query is
+	// run again with the combined role
+	if res.q.roleArg && strings.ContainsRune(res.role, ',') {
+		if role, err = c.gj.matchedRole(c, res.role); err != nil {
+			return res, err
+		}
+		if res, err = c.resolveSQL(query, vars, role); err != nil {
+			return res, err
+		}
+	}
+
 	if c.gj.conf.Debug {
 		c.debugLog(&res.q.st)
 	}
@@ -145,17 +171,37 @@ func (c *scontext) resolveSQL(query string, vars []byte, role string) (qres, err
 	}
 	defer conn.Close()
 
-	if c.gj.conf.SetUserID {
-		if err := c.setLocalUserID(conn); err != nil {
+	var tx *sql.Tx
+	var db dbConn = conn
+
+	// run the request in a transaction to keep the local role
+	// and settings from leaking into other requests
+	if c.gj.sessionTx() {
+		if tx, err = conn.BeginTx(c, nil); err != nil {
 			return res, err
 		}
+		defer tx.Rollback() //nolint: errcheck
+		db = tx
+	}
+
+	ur, err := c.gj.userRole(c, res.role)
+	if err != nil {
+		return res, err
 	}
 
-	if v := c.Value(UserRoleKey); v != nil {
-		res.role = v.(string)
+	if ur != "" {
+		res.role = ur
 
-	} else if c.gj.abacEnabled && c.op == qcode.QTMutation {
-		res.role, err = c.executeRoleQuery(conn)
+	} else if c.gj.abacEnabled && (c.op == qcode.QTMutation || hasActiveRole(c)) &&
+		(res.role == "user" || res.role == "anon") {
+		// the role is found before the query is compiled for mutations
+		// and when the client selects one of the roles matched
+		if tx != nil {
+			err = c.setLocalSettings(tx, res.role)
+		}
+		if err == nil {
+			res.role, err = c.executeRoleQuery(db)
+		}
 	}
 
 	if err != nil {
@@ -166,28 +212,82 @@ func (c *scontext) resolveSQL(query string, vars []byte, role string) (qres, err
 		return res, err
 	}
 
-	args, err := c.gj.argList(c, cq.st.md, vars, c.rc)
+	avars, err := c.gj.encryptInput(cq.st.qc, vars)
+	if err != nil {
+		return res, err
+	}
+
+	args, err := c.gj.argList(c, cq.st.md, avars, c.rc)
 	if err != nil {
 		return res, err
 	}
 
+	if c.op == qcode.QTMutation && c.gj.conf.AuditTable != "" {
+		if err = c.auditArgs(cq, &args, avars, res.role); err != nil {
+			return res, err
+		}
+	}
+
 	// var stime time.Time
 
 	// if c.gj.conf.EnableTracing {
 	// 	stime = time.Now()
 	// }
 
-	row := conn.QueryRowContext(c, cq.st.sql, args.values...)
+	if tx != nil {
+		if err = c.setSession(tx, res.role); err != nil {
+			return res, err
+		}
+	}
+
+	// Mutations with check expressions return no rows when a check
+	// fails or a versioned row was changed so they are run in a
+	// transaction that is then rolled back
+	checks := hasChecks(cq.st.qc)
+	versioned := hasVersionCheck(cq.st.qc)
+
+	if (checks || versioned) && tx == nil {
+		if tx, err = conn.BeginTx(c, nil); err != nil {
+			return res, err
+		}
+		defer tx.Rollback() //nolint: errcheck
+		db = tx
+	}
+
+	row := db.QueryRowContext(c, cq.st.sql, args.values...)
 	if cq.roleArg {
 		err = row.Scan(&res.role, &res.data)
 	} else {
 		err = row.Scan(&res.data)
 	}
 
+	if err == nil && cq.roleArg && strings.ContainsRune(res.role, ',') {
+		return res, nil
+	}
+
 	if err == sql.ErrNoRows {
+		switch {
+		case checks:
+			err = errCheckFailed
+		case versioned:
+			err = errConflict
+		}
+	}
+
+	if err != nil {
 		return res, err
-	} else if err != nil {
-		return res, err
+	}
+
+	if tx != nil {
+		if err = tx.Commit(); err != nil {
+			return res, err
+		}
+	}
+
+	// the role for attribute based access control is only known
+	// once the query is run
+	if cq.roleArg && !c.gj.queryRoleAllowed(cq, res.role) {
+		return res, fmt.Errorf("query not allowed for role: %s", res.role)
 	}
 
 	cur, err := c.gj.encryptCursor(cq.st.qc, res.data)
@@ -195,7 +295,13 @@ func (c *scontext) resolveSQL(query string, vars []byte, role string) (qres, err
 		return res, err
 	}
 
-	res.data = cur.data
+	if res.data, err = c.gj.decryptColumns(cq.st.qc, cur.data); err != nil {
+		return res, err
+	}
+
+	if res.data, err = c.gj.encodeGlobalIDs(cq.st.qc, res.data); err != nil {
+		return res, err
+	}
 
 	if c.gj.allowList != nil {
 		if err := c.gj.allowList.Set(vars, query); err != nil {
@@ -218,39 +324,50 @@ func (c *scontext) resolveSQL(query string, vars []byte, role string) (qres, err
 	return res, nil
 }
 
-func (c *scontext) executeRoleQuery(conn *sql.Conn) (string, error) {
+func hasChecks(qc *qcode.QCode) bool {
+	if qc.Type != qcode.QTMutation {
+		return false
+	}
+	for _, m := range qc.Mutates {
+		if m.Check != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVersionCheck(qc *qcode.QCode) bool {
+	if qc.Type != qcode.QTMutation {
+		return false
+	}
+	for _, m := range qc.Mutates {
+		if m.Type == qcode.MTUpdate && m.Ti.VersionCol.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *scontext) executeRoleQuery(db dbConn) (string, error) {
 	var role string
 	var ar args
 	var err error
 
 	if c.Value(UserIDKey) == nil {
-		return "anon", nil
+		return c.gj.matchedRole(c, "anon")
 	}
 
 	if ar, err = c.gj.roleQueryArgList(c); err != nil {
 		return "", err
 	}
 
-	err = conn.QueryRowContext(c, c.gj.roleStmt, ar.values...).Scan(&role)
-	return role, err
-}
-
-func (c *scontext) setLocalUserID(conn *sql.Conn) error {
-	var err error
-
-	if v := c.Value(UserIDKey); v == nil {
-		return nil
-	} else {
-		switch v1 := v.(type) {
-		case string:
-			_, err = conn.ExecContext(c, `SET SESSION "user.id" = '`+v1+`'`)
-
-		case int:
-			_, err = conn.ExecContext(c, `SET SESSION "user.id" = `+strconv.Itoa(v1))
-		}
+	err = db.QueryRowContext(c, c.gj.roleStmt, ar.values...).Scan(&role)
+	if err != nil {
+		return "", err
 	}
 
-	return err
+	// all the roles matched are returned as a comma separated list
+	return c.gj.matchedRole(c, role)
 }
 
 func (r *Result) Operation() OpType {
@@ -282,6 +399,15 @@ func (r *Result) SQL() string {
 	return r.sql
 }
 
+// Cost returns the weight of the query used for rate limiting, it's based on
+// the number of tables selected and the rows each can return.
+func (r *Result) Cost() int {
+	if r.cost == 0 {
+		return 1
+	}
+	return r.cost
+}
+
 // func (c *scontext) addTrace(sel []qcode.Select, id int32, st time.Time) {
 // 	et := time.Now()
 // 	du := et.Sub(st)
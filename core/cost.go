@@ -0,0 +1,43 @@
+package core
+
+import "github.com/dosco/graphjin/core/internal/qcode"
+
+// rowsPerCost is the number of rows a select can return for each unit
+// of cost charged
+const rowsPerCost = 100
+
+// queryCost returns a weight for the compiled query based on the number
+// of selects and the rows each can return. A nested select can return its
+// limit for each row of its parent so the rows are multiplied down the tree.
+// Every select costs at least 1 and every mutation adds 1 per table.
+func queryCost(qc *qcode.QCode) int {
+	if qc == nil {
+		return 1
+	}
+
+	rows := make([]int64, len(qc.Selects))
+	cost := len(qc.Mutates)
+
+	for i, sel := range qc.Selects {
+		n := int64(sel.Paging.Limit)
+
+		if sel.Singular || n <= 0 {
+			n = 1
+		}
+
+		if sel.ParentID != -1 {
+			n *= rows[sel.ParentID]
+		}
+
+		if n > rowsPerCost*rowsPerCost {
+			n = rowsPerCost * rowsPerCost
+		}
+		rows[i] = n
+		cost += int((n + rowsPerCost - 1) / rowsPerCost)
+	}
+
+	if cost == 0 {
+		cost = 1
+	}
+	return cost
+}
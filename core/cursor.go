@@ -2,11 +2,16 @@ package core
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
 
 	"github.com/dosco/graphjin/core/internal/crypto"
 	"github.com/dosco/graphjin/core/internal/qcode"
 	"github.com/dosco/graphjin/internal/jsn"
+	"golang.org/x/crypto/hkdf"
 )
 
 type cursors struct {
@@ -18,9 +23,18 @@ func (gj *GraphJin) encryptCursor(qc *qcode.QCode, data []byte) (cursors, error)
 	var keys [][]byte
 	cur := cursors{data: data}
 
+	// keys of the cursors used to fetch the next set
+	next := make(map[string]struct{})
+
 	for _, sel := range qc.Selects {
-		if sel.Paging.Cursor {
-			keys = append(keys, []byte((sel.FieldName + "_cursor")))
+		switch {
+		case sel.Paging.Connection:
+			keys = connCursorKeys(keys, sel.Conn, next)
+
+		case sel.Paging.Cursor:
+			k := sel.FieldName + "_cursor"
+			keys = append(keys, []byte(k))
+			next[k] = struct{}{}
 		}
 	}
 
@@ -42,18 +56,18 @@ func (gj *GraphJin) encryptCursor(qc *qcode.QCode, data []byte) (cursors, error)
 			val := f.Value[1 : len(f.Value)-1]
 			// save a copy of the first cursor value to use
 			// with subscriptions when fetching the next set
-			if cur.value == "" {
+			if _, ok := next[string(f.Key)]; ok && cur.value == "" {
 				cur.value = string(val)
 			}
-			v, err := crypto.Encrypt(val, &gj.encKey)
+			v, err := gj.encrypt(val, keyCursor, false)
 			if err != nil {
 				return cur, err
 			}
 
 			var b bytes.Buffer
-			b.Grow(base64.StdEncoding.EncodedLen(len(v)) + 2)
+			b.Grow(len(v) + 2)
 			b.WriteByte('"')
-			b.WriteString(base64.StdEncoding.EncodeToString(v))
+			b.WriteString(v)
 			b.WriteByte('"')
 			to[i].Value = b.Bytes()
 		} else {
@@ -70,10 +84,156 @@ func (gj *GraphJin) encryptCursor(qc *qcode.QCode, data []byte) (cursors, error)
 	return cur, nil
 }
 
-func (gj *GraphJin) decrypt(data string) ([]byte, error) {
+// connCursorKeys adds the keys of the cursors selected on a connection
+func connCursorKeys(keys [][]byte, fields []qcode.ConnField, next map[string]struct{}) [][]byte {
+	for _, f := range fields {
+		switch f.Name {
+		case "cursor", "startCursor":
+			keys = append(keys, []byte(f.FieldName))
+		case "endCursor":
+			keys = append(keys, []byte(f.FieldName))
+			next[f.FieldName] = struct{}{}
+		default:
+			keys = connCursorKeys(keys, f.Fields, next)
+		}
+	}
+	return keys
+}
+
+// keyIDLen is the length of the key id prefixed to encrypted values
+const keyIDLen = 4
+
+// keyUse is what an encryption key is used for, a separate key is
+// derived from the secret key for each use
+type keyUse int
+
+const (
+	keyCursor keyUse = iota
+	keyColumn
+	keyGlobalID
+)
+
+// subKeys are the keys derived for one use
+type subKeys struct {
+	enc   [32]byte // AES-GCM key
+	nonce [32]byte // HMAC key for the nonce of deterministic encryption
+}
+
+type encKey struct {
+	id     [keyIDLen]byte
+	uses   [3]subKeys
+	mask   [32]byte
+	legacy [32]byte // used to decrypt values without a key id
+}
+
+// initEncKeys derives the encryption keys from the secret keys, the
+// first key is the active key used to encrypt.
+func (gj *GraphJin) initEncKeys() {
+	var sk []string
+
+	if gj.conf.SecretKey != "" {
+		sk = append(sk, gj.conf.SecretKey)
+	}
+	sk = append(sk, gj.conf.SecretKeys...)
+
+	for _, v := range sk {
+		gj.encKeys = append(gj.encKeys, newEncKey(sha256.Sum256([]byte(v))))
+	}
+
+	if len(gj.encKeys) == 0 {
+		gj.encKeys = append(gj.encKeys, newEncKey(crypto.NewEncryptionKey()))
+	}
+
+	gj.maskKey = hex.EncodeToString(gj.encKeys[0].mask[:])
+
+	gj.conf.SecretKey = ""
+	gj.conf.SecretKeys = nil
+}
+
+func newEncKey(key [32]byte) encKey {
+	ek := encKey{legacy: key}
+	id := sha256.Sum256(key[:])
+	copy(ek.id[:], id[:keyIDLen])
+
+	for i, v := range []string{"cursor", "column", "global id"} {
+		ek.uses[i].enc = deriveKey(key, v)
+		ek.uses[i].nonce = deriveKey(key, v+" nonce")
+	}
+	ek.mask = deriveKey(key, "column mask")
+
+	return ek
+}
+
+// deriveKey derives a key for the purpose from the secret key using HKDF
+func deriveKey(key [32]byte, purpose string) [32]byte {
+	var k [32]byte
+
+	r := hkdf.New(sha256.New, key[:], nil, []byte("graphjin "+purpose))
+	if _, err := io.ReadFull(r, k[:]); err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// encrypt encrypts the data with the active key for the use and returns it
+// base64 encoded and prefixed with the key id. Deterministic encryption
+// always returns the same value for the same data and key.
+func (gj *GraphJin) encrypt(data []byte, u keyUse, deterministic bool) (string, error) {
+	return encryptWithKey(&gj.encKeys[0], data, u, deterministic)
+}
+
+func encryptWithKey(ek *encKey, data []byte, u keyUse, deterministic bool) (string, error) {
+	var v []byte
+	var err error
+
+	k := &ek.uses[u]
+
+	if deterministic {
+		v, err = crypto.EncryptDeterministic(data, &k.enc, &k.nonce)
+	} else {
+		v, err = crypto.Encrypt(data, &k.enc)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 0, keyIDLen+len(v))
+	b = append(b, ek.id[:]...)
+	b = append(b, v...)
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// decrypt decrypts data using the key for the use matching its key id.
+// Cursors without a known key id are tried with each legacy key in order.
+func (gj *GraphJin) decrypt(data string, u keyUse) ([]byte, error) {
 	v, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
 		return nil, err
 	}
-	return crypto.Decrypt(v, &gj.encKey)
+
+	if len(v) > keyIDLen {
+		for i := range gj.encKeys {
+			ek := &gj.encKeys[i]
+			if !bytes.Equal(v[:keyIDLen], ek.id[:]) {
+				continue
+			}
+			if pt, err := crypto.Decrypt(v[keyIDLen:], &ek.uses[u].enc); err == nil {
+				return pt, nil
+			}
+			break
+		}
+	}
+
+	if u != keyCursor {
+		return nil, errors.New("decryption failed")
+	}
+
+	for i := range gj.encKeys {
+		var pt []byte
+		if pt, err = crypto.Decrypt(v, &gj.encKeys[i].legacy); err == nil {
+			return pt, nil
+		}
+	}
+	return nil, err
 }
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
+	"github.com/dosco/graphjin/internal/jsn"
+)
+
+// encryptInput encrypts the values of encrypted columns found in the
+// mutation input before it's sent to the database.
+func (gj *GraphJin) encryptInput(qc *qcode.QCode, vars []byte) ([]byte, error) {
+	if qc.Type != qcode.QTMutation || len(vars) == 0 {
+		return vars, nil
+	}
+
+	// encrypted columns by mutation path
+	cm := make(map[string]map[string]sdata.DBColumn)
+
+	for _, m := range qc.Mutates {
+		for _, col := range m.Cols {
+			if !col.Col.Encrypted {
+				continue
+			}
+			p := strings.Join(m.Path, ".")
+			if _, ok := cm[p]; !ok {
+				cm[p] = make(map[string]sdata.DBColumn)
+			}
+			cm[p][col.FieldName] = col.Col
+		}
+	}
+
+	if len(cm) == 0 {
+		return vars, nil
+	}
+
+	fields, _, err := jsn.Tree(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := fields[qc.ActionVar]
+	if !ok {
+		return vars, nil
+	}
+
+	if fields[qc.ActionVar], err = gj.encryptInputVal(v, "", cm); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+func (gj *GraphJin) encryptInputVal(
+	v json.RawMessage,
+	path string,
+	cm map[string]map[string]sdata.DBColumn) (json.RawMessage, error) {
+
+	switch v[0] {
+	case '[':
+		var list []json.RawMessage
+		if err := json.Unmarshal(v, &list); err != nil {
+			return nil, err
+		}
+		for i := range list {
+			v1, err := gj.encryptInputVal(list[i], path, cm)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v1
+		}
+		return json.Marshal(list)
+
+	case '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(v, &obj); err != nil {
+			return nil, err
+		}
+		for k, v1 := range obj {
+			var err error
+
+			if col, ok := cm[path][k]; ok {
+				obj[k], err = gj.encryptJSONVal(v1, col.Deterministic)
+			} else if v1[0] == '{' || v1[0] == '[' {
+				obj[k], err = gj.encryptInputVal(v1, joinPath(path, k), cm)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(obj)
+	}
+
+	return v, nil
+}
+
+// encryptArg encrypts the value of a variable compared against an
+// encrypted column in a filter. The values are encrypted with each of
+// the keys and returned as a list so rows saved before the secret key
+// was rotated are still matched.
+func (gj *GraphJin) encryptArg(v interface{}) (interface{}, error) {
+	var vals []string
+
+	switch v1 := v.(type) {
+	case json.RawMessage:
+		if v1[0] == '[' {
+			var list []json.RawMessage
+			if err := json.Unmarshal(v1, &list); err != nil {
+				return nil, err
+			}
+			for i := range list {
+				s, ok, err := jsonText(list[i])
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					vals = append(vals, s)
+				}
+			}
+			break
+		}
+
+		s, ok, err := jsonText(v1)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			vals = append(vals, s)
+		}
+
+	default:
+		vals = append(vals, fmt.Sprint(v1))
+	}
+
+	list := make([]string, 0, len(vals)*len(gj.encKeys))
+
+	for i := range gj.encKeys {
+		for _, s := range vals {
+			ev, err := encryptWithKey(&gj.encKeys[i], []byte(s), keyColumn, true)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, ev)
+		}
+	}
+
+	b, err := json.Marshal(list)
+	return json.RawMessage(b), err
+}
+
+// encryptJSONVal encrypts a json value and returns it as a json string
+func (gj *GraphJin) encryptJSONVal(v json.RawMessage, deterministic bool) (json.RawMessage, error) {
+	s, ok, err := jsonText(v)
+	if err != nil || !ok {
+		return v, err
+	}
+
+	ev, err := gj.encrypt([]byte(s), keyColumn, deterministic)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ev)
+}
+
+// jsonText returns the text of a json value, false is returned for null
+func jsonText(v json.RawMessage) (string, bool, error) {
+	var s string
+
+	switch {
+	case bytes.Equal(v, []byte(`null`)):
+		return "", false, nil
+
+	case v[0] == '"':
+		if err := json.Unmarshal(v, &s); err != nil {
+			return "", false, err
+		}
+
+	default:
+		s = string(v)
+	}
+	return s, true, nil
+}
+
+// decryptColumns decrypts the values of encrypted columns in the
+// query result, the columns are found by their selection path.
+func (gj *GraphJin) decryptColumns(qc *qcode.QCode, data []byte) ([]byte, error) {
+	if !hasEncryptedCols(qc) {
+		return data, nil
+	}
+
+	match := func(col qcode.Column) bool { return col.Col.Encrypted }
+
+	return mapColumns(qc, data, match, func(_ *qcode.Select, _ qcode.Column, v json.RawMessage) (json.RawMessage, error) {
+		if len(v) < 2 || v[0] != '"' {
+			return v, nil
+		}
+
+		s, _, err := jsonText(v)
+		if err != nil {
+			return nil, err
+		}
+
+		// values saved before the column was encrypted are left as is
+		pt, err := gj.decrypt(s, keyColumn)
+		if err != nil {
+			return v, nil
+		}
+		return json.Marshal(string(pt))
+	})
+}
+
+func hasEncryptedCols(qc *qcode.QCode) bool {
+	for _, sel := range qc.Selects {
+		for _, col := range sel.Cols {
+			if !col.Base && col.Col.Encrypted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func TestDecryptColumns(t *testing.T) {
+	gj := &GraphJin{conf: &Config{SecretKey: "secret"}}
+	gj.initEncKeys()
+
+	ev, err := gj.encrypt([]byte("a@b.com"), keyColumn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qc := &qcode.QCode{
+		Roots: []int32{0},
+		Selects: []qcode.Select{
+			{ID: 0, FieldName: "users", Children: []int32{1}, Cols: []qcode.Column{
+				{FieldName: "id", Col: sdata.DBColumn{Name: "id"}},
+				{FieldName: "email", Col: sdata.DBColumn{Name: "email", Encrypted: true}},
+			}},
+			{ID: 1, FieldName: "posts", Cols: []qcode.Column{
+				{FieldName: "meta", Col: sdata.DBColumn{Name: "meta"}},
+			}},
+		},
+	}
+
+	// the email inside the json column is not a selected column
+	data := []byte(`{"users": [{"id": 1, "email": "` + ev + `", "posts": [{"meta": {"email": "` + ev + `"}}]}]}`)
+
+	v, err := gj.decryptColumns(qc, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res struct {
+		Users []struct {
+			Email string
+			Posts []struct {
+				Meta struct{ Email string }
+			}
+		}
+	}
+
+	if err := json.Unmarshal(v, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Users[0].Email != "a@b.com" {
+		t.Errorf("expected email to be decrypted got %s", res.Users[0].Email)
+	}
+
+	if res.Users[0].Posts[0].Meta.Email != ev {
+		t.Errorf("expected json column to be left as is got %s", res.Users[0].Posts[0].Meta.Email)
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	gj := &GraphJin{conf: &Config{SecretKey: "secret"}}
+	gj.initEncKeys()
+
+	ek := gj.encKeys[0]
+	keys := [][32]byte{ek.legacy, ek.mask}
+
+	for _, u := range ek.uses {
+		keys = append(keys, u.enc, u.nonce)
+	}
+
+	for i := range keys {
+		for j := i + 1; j < len(keys); j++ {
+			if keys[i] == keys[j] {
+				t.Fatalf("expected a different key for each use (%d, %d)", i, j)
+			}
+		}
+	}
+
+	v, err := gj.encrypt([]byte("products:1"), keyGlobalID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gj.decrypt(v, keyColumn); err == nil {
+		t.Error("expected a global id not to decrypt with the column key")
+	}
+}
+
+func TestEncryptArgRotatedKeys(t *testing.T) {
+	old := &GraphJin{conf: &Config{SecretKey: "old"}}
+	old.initEncKeys()
+
+	gj := &GraphJin{conf: &Config{SecretKey: "new", SecretKeys: []string{"old"}}}
+	gj.initEncKeys()
+
+	ev, err := old.encrypt([]byte("a@b.com"), keyColumn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := gj.encryptArg(json.RawMessage(`"a@b.com"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var list []string
+	if err := json.Unmarshal(v.(json.RawMessage), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("expected a value for each key got %v", list)
+	}
+
+	if list[1] != ev {
+		t.Errorf("expected the value encrypted with the old key to match")
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/qcode"
+)
+
+// encodeGlobalIDs encrypts the table name and primary key returned as the
+// id of a table into a global id. Deterministic encryption is used so an
+// object always has the same id.
+func (gj *GraphJin) encodeGlobalIDs(qc *qcode.QCode, data []byte) ([]byte, error) {
+	if !hasGlobalIDCols(qc) {
+		return data, nil
+	}
+
+	match := func(col qcode.Column) bool { return col.GlobalID }
+
+	return mapColumns(qc, data, match, func(sel *qcode.Select, _ qcode.Column, v json.RawMessage) (json.RawMessage, error) {
+		if len(v) < 2 || v[0] != '"' {
+			return v, nil
+		}
+
+		s, _, err := jsonText(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if n := strings.IndexByte(s, ':'); n == -1 || s[:n] != sel.Table {
+			return v, nil
+		}
+
+		ev, err := gj.encrypt([]byte(s), keyGlobalID, true)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(ev)
+	})
+}
+
+func hasGlobalIDCols(qc *qcode.QCode) bool {
+	for _, sel := range qc.Selects {
+		for _, col := range sel.Cols {
+			if col.GlobalID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeGlobalIDs decrypts a global id or a list of them into the
+// table name and primary key used to find the rows
+func (gj *GraphJin) decodeGlobalIDs(v interface{}) (interface{}, error) {
+	switch v1 := v.(type) {
+	case json.RawMessage:
+		var list []string
+		if err := json.Unmarshal(v1, &list); err != nil {
+			return nil, errGlobalID
+		}
+		for i := range list {
+			id, err := gj.decodeGlobalID(list[i])
+			if err != nil {
+				return nil, err
+			}
+			list[i] = id
+		}
+		b, err := json.Marshal(list)
+		return json.RawMessage(b), err
+
+	case string:
+		return gj.decodeGlobalID(v1)
+
+	default:
+		return nil, errGlobalID
+	}
+}
+
+func (gj *GraphJin) decodeGlobalID(id string) (string, error) {
+	v, err := gj.decrypt(id, keyGlobalID)
+	if err != nil || bytes.IndexByte(v, ':') == -1 {
+		return "", errGlobalID
+	}
+	return string(v), nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func TestEncodeGlobalIDs(t *testing.T) {
+	gj := &GraphJin{conf: &Config{SecretKey: "secret"}}
+	gj.initEncKeys()
+
+	qc := &qcode.QCode{
+		Roots: []int32{0},
+		Selects: []qcode.Select{
+			{ID: 0, Table: "users", FieldName: "users", Children: []int32{1}, Cols: []qcode.Column{
+				{FieldName: "id", Col: sdata.DBColumn{Name: "id"}, GlobalID: true},
+				{FieldName: "meta", Col: sdata.DBColumn{Name: "meta"}},
+			}},
+			{ID: 1, Table: "products", FieldName: "products", Cols: []qcode.Column{
+				{FieldName: "id", Col: sdata.DBColumn{Name: "id"}, GlobalID: true},
+			}},
+		},
+	}
+
+	// the id inside the json column is not a selected column
+	data := []byte(`{"users": [{"id": "users:1", "meta": {"id": "users:1"}, "products": [{"id": "products:2"}]}]}`)
+
+	v, err := gj.encodeGlobalIDs(qc, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res struct {
+		Users []struct {
+			ID       string
+			Meta     struct{ ID string }
+			Products []struct{ ID string }
+		}
+	}
+
+	if err := json.Unmarshal(v, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	u := res.Users[0]
+
+	if id, err := gj.decodeGlobalID(u.ID); err != nil || id != "users:1" {
+		t.Errorf("expected an encoded user id got %s", u.ID)
+	}
+
+	if id, err := gj.decodeGlobalID(u.Products[0].ID); err != nil || id != "products:2" {
+		t.Errorf("expected an encoded product id got %s", u.Products[0].ID)
+	}
+
+	if u.Meta.ID != "users:1" {
+		t.Errorf("expected json column to be left as is got %s", u.Meta.ID)
+	}
+}
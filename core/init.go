@@ -36,6 +36,13 @@ func (gj *GraphJin) initConfig() error {
 		tm[t.Name] = struct{}{}
 
 		t.Table = flect.Pluralize(strings.ToLower(t.Table))
+
+		for _, col := range t.Columns {
+			if (col.Encrypted || col.Deterministic) && c.SecretKey == "" && len(c.SecretKeys) == 0 {
+				return fmt.Errorf("tables: %s: secret_key required for encrypted column: %s",
+					t.Name, col.Name)
+			}
+		}
 	}
 
 	for k, v := range c.Vars {
@@ -62,6 +69,10 @@ func (gj *GraphJin) initConfig() error {
 		role.Match = sanitize(role.Match)
 		role.tm = make(map[string]*RoleTable)
 
+		for n := range role.Extends {
+			role.Extends[n] = sanitize(role.Extends[n])
+		}
+
 		for n, table := range role.Tables {
 			role.tm[table.Name] = &role.Tables[n]
 		}
@@ -89,6 +100,10 @@ func (gj *GraphJin) initConfig() error {
 		gj.roles["anon"] = &ur
 	}
 
+	if err := checkRoleExtends(gj.roles); err != nil {
+		return err
+	}
+
 	if c.RolesQuery != "" {
 		if n, ok := isASCII(c.RolesQuery); !ok {
 			return fmt.Errorf("roles_query: invalid character (%s) at %d",
@@ -134,6 +149,18 @@ func addTables(c *Config, di *sdata.DBInfo) error {
 	var err error
 
 	for _, t := range c.Tables {
+		if err := addEncryptedColumns(di, t); err != nil {
+			return err
+		}
+
+		if err := addSoftDeleteColumn(di, t); err != nil {
+			return err
+		}
+
+		if err := addVersionColumn(di, t); err != nil {
+			return err
+		}
+
 		for _, c := range t.Columns {
 			if !c.Primary {
 				continue
@@ -158,6 +185,112 @@ func addTables(c *Config, di *sdata.DBInfo) error {
 			return err
 		}
 
+		if err := addDescriptions(di, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addEncryptedColumns(di *sdata.DBInfo, t Table) error {
+	if t.Type != "" {
+		return nil
+	}
+
+	for _, c := range t.Columns {
+		if !c.Encrypted && !c.Deterministic {
+			continue
+		}
+
+		c1, err := di.GetColumn(t.Name, c.Name)
+		if err != nil {
+			return fmt.Errorf("config: encrypted column: (%s) %w", t.Name, err)
+		}
+
+		switch c1.Type {
+		case "text", "character varying", "varchar", "longtext", "mediumtext":
+		default:
+			return fmt.Errorf(
+				"config: encrypted column '%s' in table '%s' is of type '%s'. Only text types are valid",
+				c.Name, t.Name, c1.Type)
+		}
+
+		c1.Encrypted = true
+		c1.Deterministic = c.Deterministic
+	}
+	return nil
+}
+
+func addSoftDeleteColumn(di *sdata.DBInfo, t Table) error {
+	if t.SoftDelete == "" || t.Type != "" {
+		return nil
+	}
+
+	c, err := di.GetColumn(t.Name, t.SoftDelete)
+	if err != nil {
+		return fmt.Errorf("config: soft delete column: (%s) %w", t.Name, err)
+	}
+
+	if !c.IsBool() && !c.IsTimestamp() {
+		return fmt.Errorf(
+			"config: soft delete column '%s' in table '%s' is of type '%s'. Only timestamp and boolean types are valid",
+			c.Name, t.Name, c.Type)
+	}
+
+	c.SoftDelete = true
+	return nil
+}
+
+func addVersionColumn(di *sdata.DBInfo, t Table) error {
+	if t.Version == "" || t.Type != "" {
+		return nil
+	}
+
+	c, err := di.GetColumn(t.Name, t.Version)
+	if err != nil {
+		return fmt.Errorf("config: version column: (%s) %w", t.Name, err)
+	}
+
+	switch c.Type {
+	case "smallint", "integer", "int", "bigint", "int2", "int4", "int8":
+	default:
+		if !strings.HasPrefix(c.Type, "timestamp") && c.Type != "datetime" {
+			return fmt.Errorf(
+				"config: version column '%s' in table '%s' is of type '%s'. Only integer and timestamp types are valid",
+				c.Name, t.Name, c.Type)
+		}
+	}
+
+	c.Version = true
+	return nil
+}
+
+// addDescriptions sets the table and column descriptions from the
+// config for the ones without a comment in the database
+func addDescriptions(di *sdata.DBInfo, t Table) error {
+	// aliases and polymorphic tables are not database tables
+	dt, err := di.GetTable(t.Name)
+	if err != nil {
+		return nil
+	}
+
+	if dt.Description == "" {
+		dt.Description = t.Description
+	}
+
+	for _, c := range t.Columns {
+		if c.Description == "" {
+			continue
+		}
+
+		c1, err := di.GetColumn(t.Name, c.Name)
+		if err != nil {
+			return fmt.Errorf("config: column description: (%s) %w", t.Name, err)
+		}
+
+		if c1.Description == "" {
+			c1.Description = c.Description
+		}
 	}
 	return nil
 }
@@ -288,8 +421,14 @@ func addForeignKey(di *sdata.DBInfo, c Column, t Table) error {
 }
 
 func addRoles(c *Config, qc *qcode.Compiler) error {
+	rm := make(map[string]*Role, len(c.Roles))
+
+	for i := range c.Roles {
+		rm[c.Roles[i].Name] = &c.Roles[i]
+	}
+
 	for _, r := range c.Roles {
-		for _, t := range r.Tables {
+		for _, t := range resolveRoleTables(rm, r) {
 			if err := addRole(qc, r, t, c.DefaultBlock); err != nil {
 				return err
 			}
@@ -321,7 +460,9 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 			Limit:            t.Query.Limit,
 			Filters:          t.Query.Filters,
 			Columns:          t.Query.Columns,
+			Masks:            t.Query.Masks,
 			DisableFunctions: t.Query.DisableFunctions,
+			WithDeleted:      t.Query.WithDeleted,
 			Block:            t.Query.Block,
 		}
 	}
@@ -329,6 +470,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 	if t.Insert != nil {
 		insert = qcode.InsertConfig{
 			Columns: t.Insert.Columns,
+			Check:   t.Insert.Check,
 			Presets: t.Insert.Presets,
 			Block:   t.Insert.Block,
 		}
@@ -338,6 +480,7 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 		update = qcode.UpdateConfig{
 			Filters: t.Update.Filters,
 			Columns: t.Update.Columns,
+			Check:   t.Update.Check,
 			Presets: t.Update.Presets,
 			Block:   t.Update.Block,
 		}
@@ -345,10 +488,11 @@ func addRole(qc *qcode.Compiler, r Role, t RoleTable, defaultBlock bool) error {
 
 	if t.Upsert != nil {
 		upsert = qcode.UpsertConfig{
-			Filters: t.Update.Filters,
-			Columns: t.Update.Columns,
-			Presets: t.Update.Presets,
-			Block:   t.Update.Block,
+			Filters: t.Upsert.Filters,
+			Columns: t.Upsert.Columns,
+			Check:   t.Upsert.Check,
+			Presets: t.Upsert.Presets,
+			Block:   t.Upsert.Block,
 		}
 	}
 
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func TestAddDescriptions(t *testing.T) {
+	di := sdata.GetTestDBInfo()
+	di.AddDescriptions([]sdata.DBDescription{
+		{Kind: "column", Table: "products", Name: "name", Description: "From the database"},
+	})
+
+	err := addDescriptions(di, Table{
+		Name:        "products",
+		Description: "From the config",
+		Columns: []Column{
+			{Name: "name", Description: "Not used"},
+			{Name: "price", Description: "Price in cents"},
+			{Name: "user_id"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		col, exp string
+	}{
+		{"name", "From the database"},
+		{"price", "Price in cents"},
+		{"user_id", ""},
+	}
+
+	for _, v := range tests {
+		c, err := di.GetColumn("products", v.col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Description != v.exp {
+			t.Errorf("%s: expected '%s' got '%s'", v.col, v.exp, c.Description)
+		}
+	}
+
+	tb, _ := di.GetTable("products")
+	if tb.Description != "From the config" {
+		t.Errorf("expected table description from the config got '%s'", tb.Description)
+	}
+
+	// unknown columns are an error
+	err = addDescriptions(di, Table{
+		Name:    "products",
+		Columns: []Column{{Name: "missing", Description: "Not a column"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+
+	// aliases and virtual tables are skipped
+	err = addDescriptions(di, Table{Name: "me", Description: "An alias"})
+	if err != nil {
+		t.Error(err)
+	}
+}
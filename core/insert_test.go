@@ -86,6 +86,82 @@ func Example_insertWithPresets() {
 	// Output: {"product": {"id": 2001, "name": "Product 2001", "user": {"id": 3, "email": "user3@test.com"}}}
 }
 
+func Example_insertWithCheckFailed() {
+	gql := `mutation {
+		product(insert: $data) {
+			id
+			name
+		}
+	}`
+
+	vars := json.RawMessage(`{
+		"data": {
+			"id": 2010,
+			"name": "Product 2010",
+			"description": "Description for product 2010",
+			"price": 2010.50,
+			"owner_id": 5
+		}
+	}`)
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true}
+	conf.AddRoleTable("user", "products", core.Insert{
+		Check: []string{"{ owner_id: { eq: $user_id } }"},
+	})
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx := context.WithValue(context.Background(), core.UserIDKey, 3)
+	res, err := gj.GraphQL(ctx, gql, vars)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: mutation check failed
+}
+
+func Example_insertWithEncryptedColumn() {
+	gql := `mutation {
+		user(insert: $data) {
+			id
+			stripe_id
+		}
+	}`
+
+	vars := json.RawMessage(`{
+		"data": {
+			"id": 1011,
+			"email": "user1011@test.com",
+			"full_name": "User 1011",
+			"stripe_id": "payment_id_1011"
+		}
+	}`)
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true, SecretKey: "not_a_secret"}
+	conf.Tables = []core.Table{{
+		Name:    "users",
+		Columns: []core.Column{{Name: "stripe_id", Encrypted: true}},
+	}}
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx := context.WithValue(context.Background(), core.UserIDKey, 3)
+	res, err := gj.GraphQL(ctx, gql, vars)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: {"user": {"id": 1011, "stripe_id": "payment_id_1011"}}
+}
+
 func Example_bulkInsert() {
 	gql := `mutation {
 		users(insert: $data) {
@@ -29,6 +29,7 @@ type Item struct {
 	key     string
 	Query   string
 	Vars    string
+	Roles   []string
 	frags   []Frag
 }
 
@@ -103,6 +104,8 @@ func (al *List) Set(vars []byte, query string) error {
 		return errors.New("empty query")
 	}
 
+	query, roles := parseRoles(query)
+
 	items, err := parse(query)
 	if err != nil {
 		return err
@@ -110,6 +113,7 @@ func (al *List) Set(vars []byte, query string) error {
 
 	if len(items) != 0 {
 		items[0].Vars = string(vars)
+		items[0].Roles = roles
 		al.saveChan <- items[0]
 	}
 
@@ -155,10 +159,12 @@ func (al *List) Load() ([]Item, error) {
 		if err != nil {
 			return nil, fmt.Errorf("allow list: %w", err)
 		}
-		item, err := parse(string(b))
+		q, roles := parseRoles(string(b))
+		item, err := parse(q)
 		if err != nil {
 			return nil, err
 		}
+		item[0].Roles = roles
 		items = append(items, item[0])
 	}
 
@@ -178,10 +184,12 @@ func (al *List) GetQuery(name string) (Item, error) {
 		return Item{}, err
 	}
 
-	items, err := parse(string(v))
+	q, roles := parseRoles(string(v))
+	items, err := parse(q)
 	if err != nil {
 		return Item{}, err
 	}
+	items[0].Roles = roles
 	return items[0], nil
 }
 
@@ -335,12 +343,26 @@ func (al *List) saveItem(v Item, ap string, ow bool) error {
 	}
 
 	if oq {
+		// keep the roles set on an existing query
+		if len(v.Roles) == 0 {
+			if b, err := ioutil.ReadFile(fn); err == nil {
+				_, v.Roles = parseRoles(string(b))
+			}
+		}
+
 		f, err := os.Create(fn)
 		if err != nil {
 			return err
 		}
 		defer f.Close()
 
+		if len(v.Roles) != 0 {
+			_, err = f.WriteString(fmt.Sprintf("# %s %s\n\n", rolesDirective, strings.Join(v.Roles, ", ")))
+			if err != nil {
+				return err
+			}
+		}
+
 		if v.Vars != "" {
 			var buf bytes.Buffer
 
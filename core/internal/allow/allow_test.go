@@ -1,6 +1,9 @@
 package allow
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 )
 
@@ -239,3 +242,65 @@ func TestParse2(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestParseRoles(t *testing.T) {
+	var al = `# roles: admin, Editor
+	
+	query getUsers {
+		users {
+			id
+		}
+	}`
+
+	q, roles := parseRoles(al)
+
+	if len(roles) != 2 || roles[0] != "admin" || roles[1] != "editor" {
+		t.Fatal("unexpected roles ", roles)
+	}
+
+	items, err := parse(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0].Name != "getUsers" {
+		t.Fatal("unexpected items ", items)
+	}
+}
+
+func TestSaveKeepsRoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	al, err := New(path.Join(dir, "allow.list"), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := al.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	q := "query getUsers {\n  users {\n    id\n  }\n}"
+	fn := path.Join(al.queryPath, "getUsers")
+
+	if err := ioutil.WriteFile(fn, []byte("# roles: admin\n\n"+q), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := al.saveItem(Item{Name: "getUsers", Query: q}, dir, true); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := al.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || len(items[0].Roles) != 1 || items[0].Roles[0] != "admin" {
+		t.Fatal("roles not kept ", items)
+	}
+}
@@ -85,3 +85,34 @@ func isGraphQL(s string) bool {
 		strings.HasPrefix(s, "mutation") ||
 		strings.HasPrefix(s, "subscription")
 }
+
+// rolesDirective is a comment directive that limits a saved query
+// to the listed roles (eg. # roles: admin, editor)
+const rolesDirective = "roles:"
+
+// parseRoles returns the roles listed in the roles directive and the
+// query with the directive removed.
+func parseRoles(b string) (string, []string) {
+	var roles []string
+	var sb strings.Builder
+
+	for _, l := range strings.SplitAfter(b, "\n") {
+		v := strings.TrimSpace(l)
+
+		if strings.HasPrefix(v, "#") {
+			v = strings.TrimSpace(v[1:])
+
+			if strings.HasPrefix(v, rolesDirective) {
+				for _, r := range strings.Split(v[len(rolesDirective):], ",") {
+					if r = strings.ToLower(strings.TrimSpace(r)); r != "" {
+						roles = append(roles, r)
+					}
+				}
+				continue
+			}
+		}
+		sb.WriteString(l)
+	}
+
+	return sb.String(), roles
+}
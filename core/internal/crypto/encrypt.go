@@ -15,7 +15,9 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"io"
 )
@@ -54,6 +56,29 @@ func Encrypt(plaintext []byte, key *[32]byte) (ciphertext []byte, err error) {
 	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
+// EncryptDeterministic encrypts data using 256-bit AES-GCM with a nonce
+// derived from a HMAC-SHA256 of the plaintext keyed with nonceKey. The same
+// plaintext and keys always give the same ciphertext which allows for equality
+// lookups at the cost of revealing when two values are equal. Output takes the
+// same form as Encrypt() and can be decrypted using Decrypt().
+func EncryptDeterministic(plaintext []byte, key, nonceKey *[32]byte) (ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, nonceKey[:])
+	mac.Write(plaintext) //nolint: errcheck
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
 // Decrypt decrypts data using 256-bit AES-GCM.  This both hides the content of
 // the data and provides a check that it hasn't been altered. Expects input
 // form nonce|ciphertext|tag where '|' indicates concatenation.
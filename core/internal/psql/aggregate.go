@@ -0,0 +1,42 @@
+//nolint:errcheck
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/internal/qcode"
+)
+
+// renderAggregateBaseSelect renders the aggregates over the rows of a table
+// or relationship as a single row. When rows are limited, skipped or distinct
+// they are selected in a subquery first so the aggregates only cover those rows.
+func (c *compilerContext) renderAggregateBaseSelect(sel *qcode.Select) {
+	c.w.WriteString(`SELECT `)
+	c.renderBaseColumns(sel)
+
+	if !aggregateSubquery(sel) {
+		c.renderFrom(sel)
+		c.renderJoinTables(sel.Rel)
+		c.renderWhere(sel)
+		c.renderHaving(sel)
+		return
+	}
+
+	c.w.WriteString(` FROM (SELECT `)
+	c.renderDistinctOn(sel)
+	quoted(c.w, sel.Table)
+	c.w.WriteString(`.*`)
+	c.renderFrom(sel)
+	c.renderJoinTables(sel.Rel)
+	c.renderWhere(sel)
+	c.renderOrderBy(sel)
+	c.renderLimit(sel)
+	c.w.WriteString(`) AS `)
+	quoted(c.w, sel.Table)
+	c.renderHaving(sel)
+}
+
+func aggregateSubquery(sel *qcode.Select) bool {
+	return !sel.Paging.NoLimit ||
+		sel.Paging.Offset != 0 ||
+		sel.Paging.OffsetVar != "" ||
+		len(sel.DistinctOn) != 0
+}
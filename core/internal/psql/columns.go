@@ -5,7 +5,7 @@ import (
 	"github.com/dosco/graphjin/core/internal/sdata"
 )
 
-func (c *compilerContext) renderColumns(sel *qcode.Select) {
+func (c *compilerContext) renderColumns(sel *qcode.Select) int {
 	i := 0
 	for _, col := range sel.Cols {
 		if col.Base {
@@ -14,7 +14,13 @@ func (c *compilerContext) renderColumns(sel *qcode.Select) {
 		if i != 0 {
 			c.w.WriteString(", ")
 		}
-		colWithTableID(c.w, sel.Table, sel.ID, col.Col.Name)
+		if col.Mask != qcode.MaskTypeNone {
+			c.renderMaskedColumn(sel, col)
+		} else if col.GlobalID {
+			c.renderGlobalID(sel, col)
+		} else {
+			colWithTableID(c.w, sel.Table, sel.ID, col.Col.Name)
+		}
 		alias(c.w, col.FieldName)
 		i++
 	}
@@ -34,10 +40,73 @@ func (c *compilerContext) renderColumns(sel *qcode.Select) {
 		i++
 	}
 
-	c.renderJoinColumns(sel, i)
+	return c.renderJoinColumns(sel, i)
 }
 
-func (c *compilerContext) renderJoinColumns(sel *qcode.Select, n int) {
+// MaskKeyParam is the name of the param holding the key used
+// to hash the values of columns with the hash mask
+const MaskKeyParam = "_mask_key"
+
+// renderMaskedColumn renders a column wrapped in the masking
+// expression set for it in the role config so the unmasked value
+// never leaves the database. Hashed values are keyed so they can't
+// be reversed by hashing guesses.
+func (c *compilerContext) renderMaskedColumn(sel *qcode.Select, col qcode.Column) {
+	col1 := func() { colWithTableID(c.w, sel.Table, sel.ID, col.Col.Name) }
+
+	if c.md.ct == "mysql" {
+		switch col.Mask {
+		case qcode.MaskTypeEmail:
+			c.w.WriteString(`CONCAT(LEFT(`)
+			col1()
+			c.w.WriteString(`, 1), '***@', IF(LOCATE('@', `)
+			col1()
+			c.w.WriteString(`) > 0, SUBSTRING_INDEX(`)
+			col1()
+			c.w.WriteString(`, '@', -1), ''))`)
+		case qcode.MaskTypeLast4:
+			c.w.WriteString(`CONCAT(REPEAT('*', GREATEST(CHAR_LENGTH(`)
+			col1()
+			c.w.WriteString(`) - 4, 0)), RIGHT(`)
+			col1()
+			c.w.WriteString(`, 4))`)
+		case qcode.MaskTypeHash:
+			c.w.WriteString(`SHA2(CONCAT(`)
+			c.renderParam(Param{Name: MaskKeyParam, Type: "text"})
+			c.w.WriteString(`, `)
+			col1()
+			c.w.WriteString(`), 256)`)
+		default:
+			c.w.WriteString(`NULL`)
+		}
+		return
+	}
+
+	switch col.Mask {
+	case qcode.MaskTypeEmail:
+		c.w.WriteString(`(left(`)
+		col1()
+		c.w.WriteString(`::text, 1) || '***@' || split_part(`)
+		col1()
+		c.w.WriteString(`::text, '@', 2))`)
+	case qcode.MaskTypeLast4:
+		c.w.WriteString(`(repeat('*', greatest(length(`)
+		col1()
+		c.w.WriteString(`::text) - 4, 0)) || right(`)
+		col1()
+		c.w.WriteString(`::text, 4))`)
+	case qcode.MaskTypeHash:
+		c.w.WriteString(`encode(hmac(`)
+		col1()
+		c.w.WriteString(`::text, `)
+		c.renderParam(Param{Name: MaskKeyParam, Type: "text"})
+		c.w.WriteString(` :: text, 'sha256'), 'hex')`)
+	default:
+		c.w.WriteString(`NULL`)
+	}
+}
+
+func (c *compilerContext) renderJoinColumns(sel *qcode.Select, n int) int {
 	i := n
 	for _, cid := range sel.Children {
 		csel := &c.qc.Selects[cid]
@@ -72,7 +141,7 @@ func (c *compilerContext) renderJoinColumns(sel *qcode.Select, n int) {
 			}
 
 			// return the cursor for the this child selector as part of the parents json
-			if csel.Paging.Cursor {
+			if csel.Paging.Cursor && !csel.Paging.Connection {
 				c.w.WriteString(`, __sj_`)
 				int32String(c.w, csel.ID)
 				c.w.WriteString(`.cursor AS `)
@@ -82,6 +151,7 @@ func (c *compilerContext) renderJoinColumns(sel *qcode.Select, n int) {
 		}
 		i++
 	}
+	return i
 }
 
 func (c *compilerContext) renderUnionColumn(sel, csel *qcode.Select) {
@@ -113,13 +183,17 @@ func (c *compilerContext) renderRemoteRelColumns(sel, csel *qcode.Select) {
 }
 
 func (c *compilerContext) renderFunction(sel *qcode.Select, fn qcode.Function) {
-	switch fn.Name {
-	case "search_rank":
+	switch {
+	case fn.Window != nil:
+		c.renderWindowFunction(sel, fn)
+	case fn.Name == "search_rank":
 		c.renderFunctionSearchRank(sel, fn)
-	case "search_headline":
+	case fn.Name == "search_headline":
 		c.renderFunctionSearchHeadline(sel, fn)
+	case fn.Name == "date_trunc":
+		c.renderFunctionDateTrunc(sel, fn)
 	default:
-		c.renderOtherFunction(sel, fn)
+		c.renderOtherFunction(sel.Table, fn)
 	}
 	alias(c.w, fn.FieldName)
 }
@@ -153,11 +227,114 @@ func (c *compilerContext) renderFunctionSearchHeadline(sel *qcode.Select, fn qco
 	c.w.WriteString(`))`)
 }
 
-func (c *compilerContext) renderOtherFunction(sel *qcode.Select, fn qcode.Function) {
+// renderWindowFunction renders a window function over the rows of the
+// select, for example rank() OVER (PARTITION BY ... ORDER BY ...)
+func (c *compilerContext) renderWindowFunction(sel *qcode.Select, fn qcode.Function) {
 	c.w.WriteString(fn.Name)
 	c.w.WriteString(`(`)
+	if fn.Col.Name != "" {
+		colWithTable(c.w, sel.Table, fn.Col.Name)
+	}
+	if fn.Arg != "" {
+		c.w.WriteString(`, `)
+		c.w.WriteString(fn.Arg)
+	}
+	c.w.WriteString(`) OVER (`)
+
+	w := fn.Window
+	if len(w.Partition) != 0 {
+		c.w.WriteString(`PARTITION BY `)
+		for i, col := range w.Partition {
+			if i != 0 {
+				c.w.WriteString(`, `)
+			}
+			colWithTable(c.w, sel.Table, col.Name)
+		}
+	}
+	if len(w.OrderBy) != 0 {
+		if len(w.Partition) != 0 {
+			c.w.WriteString(` `)
+		}
+		c.w.WriteString(`ORDER BY `)
+		c.renderOrderByCols(sel.Table, w.OrderBy)
+	}
+	c.w.WriteString(`)`)
+}
+
+// renderFunctionDateTrunc renders a time bucket for example created_at_by_day
+func (c *compilerContext) renderFunctionDateTrunc(sel *qcode.Select, fn qcode.Function) {
+	c.w.WriteString(`date_trunc(`)
+	squoted(c.w, fn.Arg)
+	c.w.WriteString(`, `)
 	colWithTable(c.w, sel.Table, fn.Col.Name)
-	_, _ = c.w.WriteString(`)`)
+	c.w.WriteString(`)`)
+}
+
+func (c *compilerContext) renderOtherFunction(table string, fn qcode.Function) {
+	col := func() {
+		if fn.Col.Name == "" {
+			c.w.WriteString(`*`)
+		} else {
+			colWithTable(c.w, table, fn.Col.Name)
+		}
+	}
+	mysql := (c.md.ct == "mysql")
+
+	switch {
+	case fn.Name == "count_distinct":
+		c.w.WriteString(`count(DISTINCT `)
+		col()
+		c.w.WriteString(`)`)
+
+	case fn.Name == "percentile_cont", fn.Name == "percentile_disc":
+		c.w.WriteString(fn.Name)
+		c.w.WriteString(`(`)
+		c.w.WriteString(fn.Arg)
+		c.w.WriteString(`) WITHIN GROUP (ORDER BY `)
+		col()
+		c.w.WriteString(`)`)
+
+	case fn.Name == "mode":
+		c.w.WriteString(`mode() WITHIN GROUP (ORDER BY `)
+		col()
+		c.w.WriteString(`)`)
+
+	case fn.Name == "string_agg" && mysql:
+		c.w.WriteString(`group_concat(`)
+		col()
+		c.w.WriteString(` SEPARATOR `)
+		squoted(c.w, fn.Arg)
+		c.w.WriteString(`)`)
+
+	case fn.Name == "string_agg":
+		c.w.WriteString(`string_agg(`)
+		col()
+		c.w.WriteString(` :: text, `)
+		squoted(c.w, fn.Arg)
+		c.w.WriteString(`)`)
+
+	case fn.Name == "array_agg" && mysql:
+		c.w.WriteString(`json_arrayagg(`)
+		col()
+		c.w.WriteString(`)`)
+
+	// booleans are stored as 0 or 1 in mysql
+	case fn.Name == "bool_and" && mysql:
+		c.w.WriteString(`min(`)
+		col()
+		c.w.WriteString(`)`)
+
+	case fn.Name == "bool_or" && mysql:
+		c.w.WriteString(`max(`)
+		col()
+		c.w.WriteString(`)`)
+
+	default:
+		c.w.WriteString(fn.Name)
+		c.w.WriteString(`(`)
+		col()
+		c.w.WriteString(`)`)
+	}
 }
 
 func (c *compilerContext) renderBaseColumns(sel *qcode.Select) {
@@ -0,0 +1,176 @@
+//nolint:errcheck
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+// renderConnectionSelect renders a Relay style connection with the rows
+// returned as edges. One row more than the limit is fetched to find if
+// there is another page, it's left out of the edges.
+func (c *compilerContext) renderConnectionSelect(sel *qcode.Select) {
+	c.w.WriteString(`SELECT `)
+	c.renderConnObject(sel, sel.Conn, "")
+	c.w.WriteString(` as json FROM (`)
+}
+
+func (c *compilerContext) renderConnObject(sel *qcode.Select, fields []qcode.ConnField, parent string) {
+	c.w.WriteString(`jsonb_build_object(`)
+	for i, f := range fields {
+		if i != 0 {
+			c.w.WriteString(`, `)
+		}
+		squoted(c.w, f.FieldName)
+		c.w.WriteString(`, `)
+
+		switch f.Name {
+		case "__typename":
+			switch parent {
+			case "edges":
+				squoted(c.w, sel.Ti.Singular+"Edge")
+			case "pageInfo":
+				squoted(c.w, "PageInfo")
+			default:
+				squoted(c.w, sel.Ti.Singular+"Connection")
+			}
+
+		case "edges":
+			c.w.WriteString(`coalesce(jsonb_agg(`)
+			c.renderConnObject(sel, f.Fields, f.Name)
+			c.w.WriteString(` ORDER BY __sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__rn)`)
+			c.renderConnPageFilter(sel)
+			c.w.WriteString(`, '[]')`)
+
+		case "pageInfo":
+			c.renderConnObject(sel, f.Fields, f.Name)
+
+		case "totalCount":
+			c.renderTotalCount(sel)
+
+		case "cursor":
+			c.w.WriteString(`__sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__cursor`)
+
+		case "node":
+			c.w.WriteString(`__sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.json`)
+
+		case "hasNextPage", "hasPreviousPage":
+			// rows after the limit are in the direction of the paging
+			if (f.Name == "hasNextPage") == (sel.Paging.Type != qcode.PTBackward) {
+				c.renderConnHasMore(sel)
+			} else {
+				c.renderConnHasSkipped(sel)
+			}
+
+		case "startCursor":
+			c.w.WriteString(`(array_agg(__sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__cursor ORDER BY __sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__rn))[1]`)
+
+		case "endCursor":
+			c.w.WriteString(`(array_agg(__sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__cursor ORDER BY __sj_`)
+			int32String(c.w, sel.ID)
+			c.w.WriteString(`.__rn DESC)`)
+			c.renderConnPageFilter(sel)
+			c.w.WriteString(`)[1]`)
+		}
+	}
+	c.w.WriteString(`)`)
+}
+
+// renderConnPageFilter leaves out the extra row fetched after the limit
+func (c *compilerContext) renderConnPageFilter(sel *qcode.Select) {
+	if sel.Paging.NoLimit {
+		return
+	}
+	c.w.WriteString(` FILTER (WHERE __sj_`)
+	int32String(c.w, sel.ID)
+	c.w.WriteString(`.__rn <= `)
+	c.renderLimitVal(sel)
+	c.w.WriteString(`)`)
+}
+
+// renderConnHasMore renders true if there are rows after the limit
+func (c *compilerContext) renderConnHasMore(sel *qcode.Select) {
+	if sel.Paging.NoLimit {
+		c.w.WriteString(`false`)
+		return
+	}
+	c.w.WriteString(`(count(*) > `)
+	c.renderLimitVal(sel)
+	c.w.WriteString(`)`)
+}
+
+// renderConnHasSkipped renders true if rows were skipped using
+// a cursor or an offset to get to this page
+func (c *compilerContext) renderConnHasSkipped(sel *qcode.Select) {
+	switch {
+	case sel.Paging.Type != qcode.PTOffset:
+		c.w.WriteString(`(`)
+		c.renderParam(Param{Name: "cursor", Type: "text"})
+		c.w.WriteString(` IS NOT NULL)`)
+
+	case sel.Paging.OffsetVar != "":
+		c.w.WriteString(`(`)
+		c.renderParam(Param{Name: sel.Paging.OffsetVar, Type: "integer"})
+		c.w.WriteString(` > 0)`)
+
+	case sel.Paging.Offset != 0:
+		c.w.WriteString(`true`)
+
+	default:
+		c.w.WriteString(`false`)
+	}
+}
+
+// renderRowCursor renders the cursor and the position of each row
+func (c *compilerContext) renderRowCursor(sel *qcode.Select, n int) {
+	if n != 0 {
+		c.w.WriteString(`, `)
+	}
+	c.w.WriteString(`CONCAT_WS(','`)
+	for _, ob := range sel.OrderBy {
+		c.w.WriteString(`, `)
+		colWithTableID(c.w, sel.Table, sel.ID, ob.Col.Name)
+	}
+	c.w.WriteString(`) AS __cursor, row_number() OVER() AS __rn`)
+}
+
+// renderTotalCount renders the count of all the rows the connection
+// can return ignoring the cursor and the limit
+func (c *compilerContext) renderTotalCount(sel *qcode.Select) {
+	c.w.WriteString(`(SELECT count(*) FROM (`)
+	c.renderCursorCTE(sel)
+	c.w.WriteString(`SELECT `)
+	c.renderDistinctOn(sel)
+	c.renderBaseColumns(sel)
+	c.renderFrom(sel)
+	c.renderJoinTables(sel.Rel)
+
+	if sel.Rel.Type != sdata.RelRecursive {
+		c.skipSeek = true
+		c.renderWhere(sel)
+		c.skipSeek = false
+	}
+
+	c.renderGroupBy(sel)
+	c.renderHaving(sel)
+	c.w.WriteString(`) AS __cnt)`)
+}
+
+// isSeekExp returns true for the expression added to filter
+// rows using the values in the cursor
+func isSeekExp(ex *qcode.Exp) bool {
+	return ex.Op == qcode.OpOr && len(ex.Children) != 0 &&
+		ex.Children[0].Table == "__cur"
+}
@@ -306,11 +306,26 @@ func nestedInsertRecursive(t *testing.T) {
 	compileGQLToPSQL(t, gql, vars, "user")
 }
 
+func simpleInsertWithCheck(t *testing.T) {
+	gql := `mutation {
+		product(insert: $data) {
+			id
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{"name": "Apple", "price": 1.25, "user_id": 5}`),
+	}
+
+	compileGQLToPSQL(t, gql, vars, "checked_user")
+}
+
 func TestCompileInsert(t *testing.T) {
 	t.Run("simpleInsert", simpleInsert)
 	t.Run("singleInsert", singleInsert)
 	t.Run("bulkInsert", bulkInsert)
 	t.Run("simpleInsertWithPresets", simpleInsertWithPresets)
+	t.Run("simpleInsertWithCheck", simpleInsertWithCheck)
 	t.Run("nestedInsertManyToMany", nestedInsertManyToMany)
 	t.Run("nestedInsertOneToMany", nestedInsertOneToMany)
 	t.Run("nestedInsertOneToOne", nestedInsertOneToOne)
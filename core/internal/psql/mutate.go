@@ -44,7 +44,174 @@ func (co *Compiler) compileMutation(
 	}
 
 	c.renderMultiUnionStmt()
+
+	if co.auditTable != "" {
+		c.renderAudit()
+	}
+
 	co.CompileQuery(w, qc, c.md)
+	c.renderChecks()
+}
+
+// Names of the params used to record the mutation in the audit table
+const (
+	AuditOpParam     = "_audit_op"
+	AuditHashParam   = "_audit_hash"
+	AuditRoleParam   = "_audit_role"
+	AuditUserIDParam = "_audit_user_id"
+	AuditVarsParam   = "_audit_vars"
+)
+
+// renderAudit inserts a row into the audit table with the tables
+// written to and the primary keys of the rows changed. Being part of
+// the mutation statement it's written in the same transaction.
+func (c *compilerContext) renderAudit() {
+	var tables []sdata.DBTableInfo
+	tm := make(map[string][]qcode.Mutate)
+
+	for _, m := range c.qc.Mutates {
+		switch m.Type {
+		case qcode.MTInsert, qcode.MTUpdate, qcode.MTUpsert, qcode.MTDelete:
+		case qcode.MTConnect, qcode.MTDisconnect:
+			if m.RelPC.Type != sdata.RelOneToMany {
+				continue
+			}
+		default:
+			continue
+		}
+		if _, ok := tm[m.Ti.Name]; !ok {
+			tables = append(tables, m.Ti)
+		}
+		tm[m.Ti.Name] = append(tm[m.Ti.Name], m)
+	}
+
+	c.w.WriteString(`, "_sg_audit" AS (INSERT INTO `)
+	quoted(c.w, c.auditTable)
+	c.w.WriteString(` ("operation", "query_hash", "role", "user_id", "variables", "tables", "row_ids", "created_at") SELECT `)
+
+	for _, v := range []string{AuditOpParam, AuditHashParam, AuditRoleParam, AuditUserIDParam} {
+		c.renderParam(Param{Name: v, Type: "text"})
+		c.w.WriteString(` :: text, `)
+	}
+	c.renderParam(Param{Name: AuditVarsParam, Type: "json"})
+	c.w.WriteString(` :: jsonb, '[`)
+
+	for i, ti := range tables {
+		if i != 0 {
+			c.w.WriteString(`, `)
+		}
+		c.w.WriteString(`"`)
+		c.w.WriteString(ti.Name)
+		c.w.WriteString(`"`)
+	}
+	c.w.WriteString(`]' :: jsonb, jsonb_build_object(`)
+
+	for i, ti := range tables {
+		if i != 0 {
+			c.w.WriteString(`, `)
+		}
+		squoted(c.w, ti.Name)
+		c.w.WriteString(`, `)
+
+		if ti.PrimaryCol.Name == "" {
+			c.w.WriteString(`NULL`)
+			continue
+		}
+		c.w.WriteString(`(SELECT jsonb_agg(`)
+		colWithTable(c.w, ti.Name, ti.PrimaryCol.Name)
+		c.w.WriteString(`) FROM `)
+
+		// a table written more than once has a cte for each write
+		switch ms := tm[ti.Name]; {
+		case len(ms) == 1 && !ms[0].Multi:
+			quoted(c.w, ti.Name)
+		case len(ms) == 1:
+			c.renderMutateCteName(ms[0])
+		default:
+			c.w.WriteString(`(`)
+			for j, m := range ms {
+				if j != 0 {
+					c.w.WriteString(` UNION ALL `)
+				}
+				c.w.WriteString(`SELECT `)
+				quoted(c.w, ti.PrimaryCol.Name)
+				c.w.WriteString(` FROM `)
+				c.renderMutateCteName(m)
+			}
+			c.w.WriteString(`) AS `)
+			quoted(c.w, ti.Name)
+		}
+		c.w.WriteString(`)`)
+	}
+	c.w.WriteString(`), now()) `)
+}
+
+// renderChecks ensures no result row is returned when any of the rows
+// written fail the check expression of their table or when an update
+// of a versioned table missed rows changed since they were read. The mutation is rolled back by
+// the caller when no result is returned.
+func (c *compilerContext) renderChecks() {
+	i := 0
+	for _, m := range c.qc.Mutates {
+		versioned := m.Type == qcode.MTUpdate && m.Ti.VersionCol.Name != ""
+
+		if m.Check == nil && !versioned {
+			continue
+		}
+		if i == 0 {
+			c.w.WriteString(` WHERE `)
+		} else {
+			c.w.WriteString(` AND `)
+		}
+		i++
+
+		if versioned {
+			c.renderVersionedCheck(m)
+
+			if m.Check == nil {
+				continue
+			}
+			c.w.WriteString(` AND `)
+		}
+
+		c.w.WriteString(`NOT EXISTS (SELECT 1 FROM `)
+		c.renderMutateCteName(m)
+		c.w.WriteString(` AS `)
+		quoted(c.w, m.Ti.Name)
+		c.w.WriteString(` WHERE (`)
+		c.renderExp(c.qc.Schema, m.Ti, m.Check, false)
+		c.w.WriteString(`) IS NOT TRUE)`)
+	}
+}
+
+// renderVersionedCheck fails when a row was changed since it was read,
+// that is when fewer rows were updated than matched the update or for
+// a list of updates than there are values in the input. Rows that don't
+// exist are not a conflict and the update then returns nothing.
+func (c *compilerContext) renderVersionedCheck(m qcode.Mutate) {
+	c.w.WriteString(`((SELECT count(*) FROM `)
+	c.renderMutateCteName(m)
+	c.w.WriteString(`) = `)
+
+	if m.Array {
+		c.w.WriteString(`(SELECT count(*) FROM "_sg_input" i`)
+		c.renderInputRecord(m)
+		c.w.WriteString(`) OR (SELECT n FROM `)
+		c.renderVersionCteName(m)
+		c.w.WriteString(`) = 0)`)
+	} else {
+		c.w.WriteString(`(SELECT n FROM `)
+		c.renderVersionCteName(m)
+		c.w.WriteString(`))`)
+	}
+}
+
+func (c *compilerContext) renderMutateCteName(m qcode.Mutate) {
+	if m.Multi {
+		renderCteNameWithSuffix(c.w, m, strconv.Itoa(int(m.MID)))
+	} else {
+		renderCteName(c.w, m)
+	}
 }
 
 func (c *compilerContext) renderMultiUnionStmt() {
@@ -199,6 +366,11 @@ func (c *compilerContext) renderDelete() {
 	c.w.WriteString(`WITH `)
 	quoted(c.w, sel.Table)
 
+	if sel.Ti.DeletedCol.Name != "" {
+		c.renderSoftDelete(sel)
+		return
+	}
+
 	c.w.WriteString(` AS (DELETE FROM `)
 	quoted(c.w, sel.Table)
 	c.w.WriteString(` WHERE `)
@@ -209,6 +381,37 @@ func (c *compilerContext) renderDelete() {
 	c.w.WriteString(`.*) `)
 }
 
+// renderSoftDelete marks the rows as deleted by setting the soft delete
+// column instead of deleting them
+func (c *compilerContext) renderSoftDelete(sel qcode.Select) {
+	col := sel.Ti.DeletedCol
+
+	c.w.WriteString(` AS (UPDATE `)
+	quoted(c.w, sel.Table)
+	c.w.WriteString(` SET `)
+	quoted(c.w, col.Name)
+
+	if col.IsBool() {
+		c.w.WriteString(` = true WHERE `)
+	} else {
+		c.w.WriteString(` = now() WHERE `)
+	}
+	c.renderExp(c.qc.Schema, sel.Ti, sel.Where.Exp, false)
+
+	c.w.WriteString(` AND (`)
+	colWithTable(c.w, sel.Table, col.Name)
+
+	if col.IsBool() {
+		c.w.WriteString(` IS DISTINCT FROM true)`)
+	} else {
+		c.w.WriteString(` IS NULL)`)
+	}
+
+	c.w.WriteString(` RETURNING `)
+	quoted(c.w, sel.Table)
+	c.w.WriteString(`.*) `)
+}
+
 func (c *compilerContext) renderConnectStmt(m qcode.Mutate) {
 	rel := m.RelPC
 
@@ -353,8 +556,18 @@ func (c *compilerContext) renderDisconnectStmt(m qcode.Mutate) {
 func (c *compilerContext) renderWhereFromJSON(m qcode.Mutate, key string) {
 	var kv map[string]json.RawMessage
 
+	// the where of a nested update of a versioned table is matched
+	// on the values under its key so the version check is the one
+	// from the input
+	val := m.Val
+	if m.Ti.VersionCol.Name != "" {
+		if v, ok := m.Data[key]; ok {
+			val = v
+		}
+	}
+
 	//TODO: Move this json parsing into qcode
-	if err := json.Unmarshal(m.Val, &kv); err != nil {
+	if err := json.Unmarshal(val, &kv); err != nil {
 		return
 	}
 
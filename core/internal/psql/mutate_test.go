@@ -3,6 +3,8 @@ package psql_test
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/dosco/graphjin/core/internal/psql"
 )
 
 func singleUpsert(t *testing.T) {
@@ -65,6 +67,63 @@ func delete(t *testing.T) {
 	compileGQLToPSQL(t, gql, vars, "user")
 }
 
+func withAudit(t *testing.T, fn func(t *testing.T)) {
+	pc := pcompile
+	pcompile = psql.NewCompiler(psql.Config{AuditTable: "audit_log"})
+	defer func() { pcompile = pc }()
+
+	fn(t)
+}
+
+func auditedDelete(t *testing.T) {
+	withAudit(t, delete)
+}
+
+func auditedNestedInsert(t *testing.T) {
+	gql := `mutation {
+		purchase(insert: $data) {
+			quantity
+			customer {
+				id
+			}
+			product {
+				id
+			}
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(` {
+			"quantity": 5,
+			"customer": { "email": "thedude@rug.com" },
+			"product": { "name": "Apple", "price": 1.25 }
+		}`),
+	}
+
+	withAudit(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, vars, "admin")
+	})
+}
+
+func auditedNestedInsertRecursive(t *testing.T) {
+	withAudit(t, nestedInsertRecursive)
+}
+
+func softDelete(t *testing.T) {
+	gql := `mutation {
+		comments(delete: true, where: { id: { eq: 1 } }) {
+			id
+			product {
+				id
+			}
+		}
+	}`
+
+	withSoftDelete(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, nil, "user")
+	})
+}
+
 // func blockedInsert(t *testing.T) {
 // 	gql := `mutation {
 // 		user(insert: $data) {
@@ -118,6 +177,10 @@ func TestCompileMutate(t *testing.T) {
 	t.Run("singleUpsertWhere", singleUpsertWhere)
 	// t.Run("bulkUpsert", bulkUpsert)
 	t.Run("delete", delete)
+	t.Run("auditedDelete", auditedDelete)
+	t.Run("auditedNestedInsert", auditedNestedInsert)
+	t.Run("auditedNestedInsertRecursive", auditedNestedInsertRecursive)
+	t.Run("softDelete", softDelete)
 	// t.Run("blockedInsert", blockedInsert)
 	// t.Run("blockedUpdate", blockedUpdate)
 }
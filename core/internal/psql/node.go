@@ -0,0 +1,107 @@
+//nolint:errcheck
+package psql
+
+import (
+	"github.com/dosco/graphjin/core/internal/qcode"
+)
+
+// renderGlobalID renders the primary key prefixed with the table name,
+// it's encrypted into the global id once the query returns
+func (c *compilerContext) renderGlobalID(sel *qcode.Select, col qcode.Column) {
+	switch c.md.ct {
+	case "mysql":
+		c.w.WriteString(`CONCAT(`)
+		squoted(c.w, sel.Table+":")
+		c.w.WriteString(`, `)
+		colWithTableID(c.w, sel.Table, sel.ID, col.Col.Name)
+		c.w.WriteString(`)`)
+	default:
+		c.w.WriteString(`(`)
+		squoted(c.w, sel.Table+":")
+		c.w.WriteString(` || `)
+		colWithTableID(c.w, sel.Table, sel.ID, col.Col.Name)
+		c.w.WriteString(` :: text)`)
+	}
+}
+
+// renderNodeSelect renders the node and nodes fields, the row found
+// for each global id is the one from the table the id belongs to
+func (c *compilerContext) renderNodeSelect(sel *qcode.Select) {
+	c.w.WriteString(`SELECT `)
+	if !sel.Singular {
+		c.w.WriteString(`coalesce(jsonb_agg(`)
+	}
+
+	i := 0
+	for _, cid := range sel.Children {
+		if c.qc.Selects[cid].SkipRender != qcode.SkipTypeNone {
+			continue
+		}
+		if i == 0 {
+			c.w.WriteString(`coalesce(`)
+		} else {
+			c.w.WriteString(`, `)
+		}
+		c.w.WriteString(`__sj_`)
+		int32String(c.w, cid)
+		c.w.WriteString(`.json`)
+		i++
+	}
+
+	if i == 0 {
+		c.w.WriteString(`NULL :: jsonb`)
+	} else {
+		c.w.WriteString(`)`)
+	}
+
+	if !sel.Singular {
+		c.w.WriteString(` ORDER BY __node_`)
+		int32String(c.w, sel.ID)
+		c.w.WriteString(`.n), '[]')`)
+	}
+
+	c.w.WriteString(` AS json FROM `)
+
+	if sel.Singular {
+		c.w.WriteString(`(VALUES(`)
+		c.renderParam(Param{Name: sel.ArgMap["id"].Val, Type: "text", GlobalID: true})
+		c.w.WriteString(` :: text)) AS __node_`)
+		int32String(c.w, sel.ID)
+		c.w.WriteString(`(id)`)
+	} else {
+		c.w.WriteString(`json_array_elements_text(`)
+		c.renderParam(Param{Name: sel.ArgMap["ids"].Val, Type: "text", IsArray: true, GlobalID: true})
+		c.w.WriteString(`) WITH ORDINALITY AS __node_`)
+		int32String(c.w, sel.ID)
+		c.w.WriteString(`(id, n)`)
+	}
+}
+
+// isNodeMember returns true for the tables selected by a node
+func (c *compilerContext) isNodeMember(sel *qcode.Select) bool {
+	return sel.Type == qcode.SelTypeMember && sel.ParentID != -1 &&
+		c.qc.Selects[sel.ParentID].Type == qcode.SelTypeNode
+}
+
+// renderNodeID renders the filter matching the primary key in the global
+// id, ids of other tables are cast to null so they match no rows
+func (c *compilerContext) renderNodeID(sel *qcode.Select) {
+	prefix := sel.Table + ":"
+	col := sel.Ti.PrimaryCol
+
+	c.w.WriteString(`((`)
+	colWithTable(c.w, sel.Table, col.Name)
+	c.w.WriteString(`) = (CASE WHEN left(__node_`)
+	int32String(c.w, sel.ParentID)
+	c.w.WriteString(`.id, `)
+	int32String(c.w, int32(len(prefix)))
+	c.w.WriteString(`) = `)
+	squoted(c.w, prefix)
+	c.w.WriteString(` THEN substr(__node_`)
+	int32String(c.w, sel.ParentID)
+	c.w.WriteString(`.id, `)
+	int32String(c.w, int32(len(prefix)+1))
+	c.w.WriteString(`) END) :: `)
+	c.w.WriteString(col.Type)
+	c.w.WriteString(`)`)
+}
@@ -22,6 +22,7 @@ const (
 
 var (
 	qcompile *qcode.Compiler
+	gcompile *qcode.Compiler // with global ids
 	pcompile *psql.Compiler
 	expected map[string][]string
 )
@@ -52,6 +53,11 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	gcompile, err = qcode.NewCompiler(schema, qcode.Config{GlobalIDs: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = qcompile.AddRole("user", "product", qcode.TRConfig{
 		Query: qcode.QueryConfig{
 			Columns: []string{"id", "name", "price", "users", "customers"},
@@ -83,6 +89,19 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	err = qcompile.AddRole("checked_user", "product", qcode.TRConfig{
+		Insert: qcode.InsertConfig{
+			Check: []string{"{ user_id: { eq: $user_id } }"},
+		},
+		Update: qcode.UpdateConfig{
+			Filters: []string{"{ user_id: { eq: $user_id } }"},
+			Check:   []string{"{ price: { gt: 0 } }"},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = qcompile.AddRole("anon", "product", qcode.TRConfig{
 		Query: qcode.QueryConfig{
 			Columns: []string{"id", "name"},
@@ -102,6 +121,15 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	err = qcompile.AddRole("limited", "product", qcode.TRConfig{
+		Query: qcode.QueryConfig{
+			Limit: 10,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = qcompile.AddRole("user", "users", qcode.TRConfig{
 		Query: qcode.QueryConfig{
 			Columns: []string{"id", "full_name", "avatar", "email", "products"},
@@ -111,6 +139,20 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	err = qcompile.AddRole("support", "users", qcode.TRConfig{
+		Query: qcode.QueryConfig{
+			Masks: map[string]string{
+				"email":              "email",
+				"phone":              "last4",
+				"full_name":          "hash",
+				"encrypted_password": "null",
+			},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = qcompile.AddRole("bad_dude", "users", qcode.TRConfig{
 		Query: qcode.QueryConfig{
 			Filters:          []string{"false"},
@@ -186,25 +228,37 @@ func TestMain(m *testing.M) {
 }
 
 func compileGQLToPSQL(t *testing.T, gql string, vars qcode.Variables, role string) {
-	if err := _compileGQLToPSQL(t, gql, vars, role); err != nil {
+	if err := _compileGQLToPSQL(t, qcompile, gql, vars, role); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func compileGQLToPSQLExpectErr(t *testing.T, gql string, vars qcode.Variables, role string) {
-	if err := _compileGQLToPSQL(t, gql, vars, role); err == nil {
+	if err := _compileGQLToPSQL(t, qcompile, gql, vars, role); err == nil {
+		t.Fatal(errors.New("we were expecting an error"))
+	}
+}
+
+func compileGlobalIDGQLToPSQL(t *testing.T, gql string, vars qcode.Variables, role string) {
+	if err := _compileGQLToPSQL(t, gcompile, gql, vars, role); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func compileGlobalIDGQLToPSQLExpectErr(t *testing.T, gql string, vars qcode.Variables, role string) {
+	if err := _compileGQLToPSQL(t, gcompile, gql, vars, role); err == nil {
 		t.Fatal(errors.New("we were expecting an error"))
 	}
 }
 
-func _compileGQLToPSQL(t *testing.T, gql string, vars qcode.Variables, role string) error {
+func _compileGQLToPSQL(t *testing.T, qco *qcode.Compiler, gql string, vars qcode.Variables, role string) error {
 	generateTestFile := false
 
 	if generateTestFile {
 		var sqlStmts []string
 
 		for i := 0; i < 100; i++ {
-			qc, err := qcompile.Compile([]byte(gql), vars, role)
+			qc, err := qco.Compile([]byte(gql), vars, role)
 			if err != nil {
 				return err
 			}
@@ -235,7 +289,7 @@ func _compileGQLToPSQL(t *testing.T, gql string, vars qcode.Variables, role stri
 	}
 
 	for i := 0; i < 200; i++ {
-		qc, err := qcompile.Compile([]byte(gql), vars, role)
+		qc, err := qco.Compile([]byte(gql), vars, role)
 		if err != nil {
 			return err
 		}
@@ -17,9 +17,11 @@ const (
 )
 
 type Param struct {
-	Name    string
-	Type    string
-	IsArray bool
+	Name      string
+	Type      string
+	IsArray   bool
+	Encrypted bool
+	GlobalID  bool
 }
 
 type Metadata struct {
@@ -30,9 +32,10 @@ type Metadata struct {
 }
 
 type compilerContext struct {
-	md *Metadata
-	w  *bytes.Buffer
-	qc *qcode.QCode
+	md       *Metadata
+	w        *bytes.Buffer
+	qc       *qcode.QCode
+	skipSeek bool
 	*Compiler
 }
 
@@ -41,14 +44,19 @@ type Variables map[string]json.RawMessage
 type Config struct {
 	Vars map[string]string
 	Type string
+
+	// AuditTable when set adds an insert into this table
+	// recording each mutation
+	AuditTable string
 }
 
 type Compiler struct {
-	vars map[string]string
+	vars       map[string]string
+	auditTable string
 }
 
 func NewCompiler(conf Config) *Compiler {
-	return &Compiler{vars: conf.Vars}
+	return &Compiler{vars: conf.Vars, auditTable: conf.AuditTable}
 }
 
 func (co *Compiler) CompileEx(qc *qcode.QCode) (Metadata, []byte, error) {
@@ -123,7 +131,7 @@ func (co *Compiler) CompileQuery(
 			c.w.WriteString(sel.FieldName)
 			c.w.WriteString(`', NULL`)
 
-			if sel.Paging.Cursor {
+			if sel.Paging.Cursor && !sel.Paging.Connection {
 				c.w.WriteString(`, '`)
 				c.w.WriteString(sel.FieldName)
 				c.w.WriteString(`_cursor', NULL`)
@@ -137,7 +145,7 @@ func (co *Compiler) CompileQuery(
 			c.w.WriteString(`.json`)
 
 			// return the cursor for the this child selector as part of the parents json
-			if sel.Paging.Cursor {
+			if sel.Paging.Cursor && !sel.Paging.Connection {
 				c.w.WriteString(`, '`)
 				c.w.WriteString(sel.FieldName)
 				c.w.WriteString(`_cursor', `)
@@ -192,7 +200,15 @@ func (c *compilerContext) renderQuery(st *IntStack, multi bool) {
 		}
 
 		if open {
-			if sel.Type != qcode.SelTypeUnion {
+			switch sel.Type {
+			case qcode.SelTypeUnion:
+				break
+
+			case qcode.SelTypeNode:
+				c.renderLateralJoin()
+				c.renderNodeSelect(sel)
+
+			default:
 				if sel.Rel.Type != sdata.RelNone || multi {
 					c.renderLateralJoin()
 				}
@@ -215,7 +231,14 @@ func (c *compilerContext) renderQuery(st *IntStack, multi bool) {
 			}
 
 		} else {
-			if sel.Type != qcode.SelTypeUnion {
+			switch sel.Type {
+			case qcode.SelTypeUnion:
+				break
+
+			case qcode.SelTypeNode:
+				c.renderLateralJoinClose(sel)
+
+			default:
 				c.renderSelectClose(sel)
 				if sel.Rel.Type != sdata.RelNone || multi {
 					c.renderLateralJoinClose(sel)
@@ -229,6 +252,10 @@ func (c *compilerContext) renderPluralSelect(sel *qcode.Select) {
 	if sel.Singular {
 		return
 	}
+	if sel.Paging.Connection {
+		c.renderConnectionSelect(sel)
+		return
+	}
 	switch c.md.ct {
 	case "mysql":
 		c.w.WriteString(`SELECT coalesce(json_arrayagg(__sj_`)
@@ -266,7 +293,11 @@ func (c *compilerContext) renderSelect(sel *qcode.Select) {
 		// Exclude the cusor values from the the generated json object since
 		// we manually use these values to build the cursor string
 		// Notice the `- '__cur_` its' what excludes fields in `to_jsonb`
-		if sel.Paging.Cursor {
+		switch {
+		case sel.Paging.Connection:
+			c.w.WriteString(`- '__cursor' - '__rn' `)
+
+		case sel.Paging.Cursor:
 			for i := range sel.OrderBy {
 				c.w.WriteString(`- '__cur_`)
 				int32String(c.w, int32(i))
@@ -279,7 +310,11 @@ func (c *compilerContext) renderSelect(sel *qcode.Select) {
 
 	// We manually insert the cursor values into row we're building outside
 	// of the generated json object so they can be used higher up in the sql.
-	if sel.Paging.Cursor {
+	switch {
+	case sel.Paging.Connection:
+		c.w.WriteString(`, __cursor, __rn `)
+
+	case sel.Paging.Cursor:
 		for i := range sel.OrderBy {
 			c.w.WriteString(`, __cur_`)
 			int32String(c.w, int32(i))
@@ -288,10 +323,14 @@ func (c *compilerContext) renderSelect(sel *qcode.Select) {
 	}
 
 	c.w.WriteString(`FROM (SELECT `)
-	c.renderColumns(sel)
+	n := c.renderColumns(sel)
 
 	// This is how we get the values to use to build the cursor.
-	if sel.Paging.Cursor {
+	switch {
+	case sel.Paging.Connection:
+		c.renderRowCursor(sel, n)
+
+	case sel.Paging.Cursor:
 		for i, ob := range sel.OrderBy {
 			c.w.WriteString(`, LAST_VALUE(`)
 			colWithTableID(c.w, sel.Table, sel.ID, ob.Col.Name)
@@ -355,6 +394,11 @@ func (c *compilerContext) renderJoin(rel sdata.DBRel) {
 }
 
 func (c *compilerContext) renderBaseSelect(sel *qcode.Select) {
+	if sel.Aggregate {
+		c.renderAggregateBaseSelect(sel)
+		return
+	}
+
 	c.renderCursorCTE(sel)
 	c.w.WriteString(`SELECT `)
 	c.renderDistinctOn(sel)
@@ -368,6 +412,7 @@ func (c *compilerContext) renderBaseSelect(sel *qcode.Select) {
 	}
 
 	c.renderGroupBy(sel)
+	c.renderHaving(sel)
 	c.renderOrderBy(sel)
 	c.renderLimit(sel)
 }
@@ -377,19 +422,17 @@ func (c *compilerContext) renderLimit(sel *qcode.Select) {
 	case sel.Paging.NoLimit:
 		break
 
-	case sel.Singular:
+	case sel.Singular && !sel.Aggregate:
 		c.w.WriteString(` LIMIT 1`)
 
-	case sel.Paging.LimitVar != "":
-		c.w.WriteString(` LIMIT LEAST(`)
-		c.renderParam(Param{Name: sel.Paging.LimitVar, Type: "integer"})
-		c.w.WriteString(`, `)
-		int32String(c.w, sel.Paging.Limit)
-		c.w.WriteString(`)`)
-
 	default:
 		c.w.WriteString(` LIMIT `)
-		int32String(c.w, sel.Paging.Limit)
+		c.renderLimitVal(sel)
+
+		// an extra row is fetched to find if there's another page
+		if sel.Paging.Connection {
+			c.w.WriteString(` + 1`)
+		}
 	}
 
 	switch {
@@ -403,6 +446,18 @@ func (c *compilerContext) renderLimit(sel *qcode.Select) {
 	}
 }
 
+func (c *compilerContext) renderLimitVal(sel *qcode.Select) {
+	if sel.Paging.LimitVar != "" {
+		c.w.WriteString(`LEAST(`)
+		c.renderParam(Param{Name: sel.Paging.LimitVar, Type: "integer"})
+		c.w.WriteString(`, `)
+		int32String(c.w, sel.Paging.Limit)
+		c.w.WriteString(`)`)
+	} else {
+		int32String(c.w, sel.Paging.Limit)
+	}
+}
+
 func (c *compilerContext) renderRecursiveCTE(sel *qcode.Select) {
 	c.w.WriteString(`WITH RECURSIVE `)
 	quoted(c.w, sel.Rel.Right.VTable)
@@ -515,7 +570,9 @@ func (c *compilerContext) renderCursorCTE(sel *qcode.Select) {
 }
 
 func (c *compilerContext) renderWhere(sel *qcode.Select) {
-	if sel.Rel.Type == sdata.RelNone && sel.Where.Exp == nil {
+	node := c.isNodeMember(sel)
+
+	if sel.Rel.Type == sdata.RelNone && sel.Where.Exp == nil && !node {
 		return
 	}
 
@@ -529,10 +586,14 @@ func (c *compilerContext) renderWhere(sel *qcode.Select) {
 		pid = sel.ParentID
 	}
 
-	c.renderRel(sel.Ti, sel.Rel, pid, sel.ArgMap)
+	if node {
+		c.renderNodeID(sel)
+	} else {
+		c.renderRel(sel.Ti, sel.Rel, pid, sel.ArgMap)
+	}
 
 	if sel.Where.Exp != nil {
-		if sel.Rel.Type != sdata.RelNone {
+		if sel.Rel.Type != sdata.RelNone || node {
 			c.w.WriteString(` AND `)
 		}
 		c.renderExp(c.qc.Schema, sel.Ti, sel.Where.Exp, false)
@@ -574,6 +635,12 @@ func (c *compilerContext) renderExp(schema *sdata.DBSchema, ti sdata.DBTableInfo
 			}
 
 		case *qcode.Exp:
+			// the cursor seek predicate is left out when counting rows
+			if c.skipSeek && isSeekExp(val) {
+				c.w.WriteString(`true`)
+				break
+			}
+
 			switch val.Op {
 			case qcode.OpFalse:
 				st.Push(val.Op)
@@ -633,11 +700,14 @@ func (c *compilerContext) renderOp(schema *sdata.DBSchema, ti sdata.DBTableInfo,
 		return
 	}
 
-	if ex.Col.Name != "" {
+	if ex.Col.Name != "" || ex.Func != "" {
 		c.w.WriteString(`((`)
-		if ex.Type == qcode.ValRef && ex.Op == qcode.OpIsNull {
+		switch {
+		case ex.Func != "":
+			c.renderOtherFunction(ti.Name, qcode.Function{Name: ex.Func, Col: ex.Col})
+		case ex.Type == qcode.ValRef && ex.Op == qcode.OpIsNull:
 			colWithTable(c.w, ex.Table, ex.Col.Name)
-		} else {
+		default:
 			colWithTable(c.w, ti.Name, ex.Col.Name)
 		}
 		c.w.WriteString(`) `)
@@ -751,24 +821,49 @@ func (c *compilerContext) renderGroupBy(sel *qcode.Select) {
 	}
 	c.w.WriteString(` GROUP BY `)
 
-	for i, col := range sel.Cols {
+	i := 0
+	for _, col := range sel.Cols {
 		if i != 0 {
 			c.w.WriteString(`, `)
 		}
 		colWithTable(c.w, sel.Table, col.Col.Name)
+		i++
+	}
+	for _, fn := range sel.Funcs {
+		if fn.Name != "date_trunc" {
+			continue
+		}
+		if i != 0 {
+			c.w.WriteString(`, `)
+		}
+		c.renderFunctionDateTrunc(sel, fn)
+		i++
 	}
 }
 
+func (c *compilerContext) renderHaving(sel *qcode.Select) {
+	if sel.Having.Exp == nil {
+		return
+	}
+	c.w.WriteString(` HAVING (`)
+	c.renderExp(c.qc.Schema, sel.Ti, sel.Having.Exp, true)
+	c.w.WriteString(`)`)
+}
+
 func (c *compilerContext) renderOrderBy(sel *qcode.Select) {
 	if len(sel.OrderBy) == 0 {
 		return
 	}
 	c.w.WriteString(` ORDER BY `)
-	for i, col := range sel.OrderBy {
+	c.renderOrderByCols(sel.Table, sel.OrderBy)
+}
+
+func (c *compilerContext) renderOrderByCols(table string, obList []qcode.OrderBy) {
+	for i, col := range obList {
 		if i != 0 {
 			c.w.WriteString(`, `)
 		}
-		colWithTable(c.w, sel.Table, col.Col.Name)
+		colWithTable(c.w, table, col.Col.Name)
 
 		switch col.Order {
 		case qcode.OrderAsc:
@@ -839,7 +934,7 @@ func (c *compilerContext) renderVal(ex *qcode.Exp, vars map[string]string) {
 
 		case ex.Op == qcode.OpIn || ex.Op == qcode.OpNotIn:
 			c.w.WriteString(`(ARRAY(SELECT json_array_elements_text(`)
-			c.renderParam(Param{Name: ex.Val, Type: ex.Col.Type, IsArray: true})
+			c.renderParam(Param{Name: ex.Val, Type: ex.Col.Type, IsArray: true, Encrypted: ex.Col.Encrypted})
 			c.w.WriteString(`))`)
 			c.w.WriteString(` :: `)
 			c.w.WriteString(ex.Col.Type)
@@ -847,7 +942,7 @@ func (c *compilerContext) renderVal(ex *qcode.Exp, vars map[string]string) {
 			return
 
 		default:
-			c.renderParam(Param{Name: ex.Val, Type: ex.Col.Type, IsArray: false})
+			c.renderParam(Param{Name: ex.Val, Type: ex.Col.Type, IsArray: false, Encrypted: ex.Col.Encrypted})
 		}
 
 	case qcode.ValRef:
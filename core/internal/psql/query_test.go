@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"testing"
+
+	"github.com/dosco/graphjin/core/internal/psql"
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
 )
 
 func simpleQuery(t *testing.T) {
@@ -561,6 +565,128 @@ func withCursor(t *testing.T) {
 	compileGQLToPSQL(t, gql, vars, "user")
 }
 
+func withConnection(t *testing.T) {
+	gql := `query {
+		products_connection(
+			first: 20
+			after: $cursor
+			order_by: { price: desc }) {
+			totalCount
+			pageInfo {
+				hasNextPage
+				hasPreviousPage
+				startCursor
+				endCursor
+			}
+			edges {
+				cursor
+				node {
+					id
+					name
+				}
+			}
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"cursor": json.RawMessage(`"0,1"`),
+	}
+
+	compileGQLToPSQL(t, gql, vars, "user")
+}
+
+func withNestedConnection(t *testing.T) {
+	gql := `query {
+		users {
+			id
+			orders: products_connection(first: $limit) {
+				__typename
+				edges {
+					node {
+						id
+						name
+					}
+				}
+				pageInfo {
+					next: hasNextPage
+				}
+			}
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "user")
+}
+
+func withConnectionSingular(t *testing.T) {
+	gql := `query {
+		product_connection {
+			edges {
+				node {
+					id
+				}
+			}
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+}
+
+func withGlobalIDs(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			name
+			user {
+				id
+				email
+			}
+		}
+	}`
+
+	compileGlobalIDGQLToPSQL(t, gql, nil, "user")
+}
+
+func withNode(t *testing.T) {
+	gql := `query {
+		node(id: $id) {
+			id
+			__typename
+			... on product {
+				name
+				price
+			}
+			... on userOutput {
+				email
+			}
+		}
+	}`
+
+	compileGlobalIDGQLToPSQL(t, gql, nil, "user")
+}
+
+func withNodes(t *testing.T) {
+	gql := `query {
+		nodes(ids: $ids) {
+			id
+			... on product {
+				name
+			}
+		}
+	}`
+
+	compileGlobalIDGQLToPSQL(t, gql, nil, "user")
+}
+
+func withNodeNoFragments(t *testing.T) {
+	gql := `query {
+		node(id: $id) {
+			id
+		}
+	}`
+
+	compileGlobalIDGQLToPSQLExpectErr(t, gql, nil, "user")
+}
+
 func jsonColumnAsTable(t *testing.T) {
 	gql := `query {
 		products {
@@ -612,6 +738,166 @@ func recursiveTableChildren(t *testing.T) {
 	compileGQLToPSQL(t, gql, vars, "user")
 }
 
+func withAggregate(t *testing.T) {
+	gql := `query {
+		users {
+			id
+			products_aggregate(where: { price: { gt: 10 } }) {
+				count
+				total: sum_price
+				max_price
+			}
+			products {
+				id
+				name
+			}
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "user")
+}
+
+func withAggregateLimit(t *testing.T) {
+	gql := `query {
+		products_aggregate(order_by: { price: desc }, limit: 5) {
+			count
+			avg_price
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "user")
+}
+
+func withAggregateRoleLimit(t *testing.T) {
+	gql := `query {
+		products_aggregate {
+			count
+			avg_price
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "limited")
+}
+
+func withAggregateColumn(t *testing.T) {
+	gql := `query {
+		users {
+			products_aggregate {
+				name
+			}
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+}
+
+func withHaving(t *testing.T) {
+	gql := `query {
+		products(having: { and: { count_id: { gt: 2 }, sum_price: { gte: 100 } } }) {
+			name
+			count_id
+			sum_price
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "user")
+}
+
+func withTimeBucket(t *testing.T) {
+	gql := `query {
+		products(having: { count: { gt: 1 } }) {
+			month: created_at_by_month
+			count_id
+			avg_price
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func withHavingColumn(t *testing.T) {
+	gql := `query {
+		products(having: { name: { eq: "apple" } }) {
+			name
+			count_id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "user")
+}
+
+func withHavingBlockedColumn(t *testing.T) {
+	gql := `query {
+		products(having: { sum_price: { gt: 10 } }) {
+			name
+			count_id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "anon")
+}
+
+func withHavingMaskedColumn(t *testing.T) {
+	gql := `query {
+		users(having: { max_full_name: { eq: "The Dude" } }) {
+			id
+			count_id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "support")
+}
+
+func withMoreAggregates(t *testing.T) {
+	gql := `query {
+		products {
+			count_distinct_user_id
+			median: percentile_cont_price(fraction: 0.5)
+			percentile_disc_price(fraction: 0.9)
+			mode_price
+			array_agg_id
+			string_agg_name(separator: ", ")
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func withPercentileNoFraction(t *testing.T) {
+	gql := `query {
+		products {
+			percentile_cont_price
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
+func withWindowFunctions(t *testing.T) {
+	gql := `query {
+		products(order_by: { price: desc }) {
+			id
+			rank(order_by: { price: desc })
+			row_number(partition_by: [user_id], order_by: { id: asc })
+			prev_price: lag_price(order_by: { id: asc }, offset: 2)
+			sum_over_price(partition_by: user_id, order_by: { id: asc })
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "admin")
+}
+
+func withWindowAndAggregate(t *testing.T) {
+	gql := `query {
+		products {
+			rank(order_by: { price: desc })
+			count_id
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "admin")
+}
+
 func nullForAuthRequiredInAnon(t *testing.T) {
 	gql := `query {
 		products {
@@ -627,6 +913,132 @@ func nullForAuthRequiredInAnon(t *testing.T) {
 	compileGQLToPSQL(t, gql, nil, "anon")
 }
 
+func maskedColumns(t *testing.T) {
+	gql := `query {
+		users {
+			id
+			full_name
+			phone
+			email
+			encrypted_password
+		}
+	}`
+
+	compileGQLToPSQL(t, gql, nil, "support")
+}
+
+func maskedFunctions(t *testing.T) {
+	gql := `query {
+		users {
+			max_phone
+		}
+	}`
+
+	compileGQLToPSQLExpectErr(t, gql, nil, "support")
+}
+
+func maskedFilters(t *testing.T) {
+	gql := []string{
+		`query { users(where: { email: { eq: $email } }) { id } }`,
+		`query { users(order_by: { phone: asc }) { id } }`,
+		`query { users(distinct: [full_name]) { id } }`,
+		`query { users { id rank(partition_by: [email]) } }`,
+	}
+
+	for _, v := range gql {
+		compileGQLToPSQLExpectErr(t, v, nil, "support")
+	}
+}
+
+// withSoftDelete runs the test with compilers for a schema where
+// comments.deleted_at is the soft delete column
+func withSoftDelete(t *testing.T, fn func(t *testing.T)) {
+	di := sdata.GetTestDBInfo()
+
+	col, err := di.GetColumn("comments", "deleted_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	col.SoftDelete = true
+
+	schema, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qc, err := qcode.NewCompiler(schema, qcode.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = qc.AddRole("admin", "comments", qcode.TRConfig{
+		Query: qcode.QueryConfig{WithDeleted: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qcomp, pcomp := qcompile, pcompile
+	qcompile, pcompile = qc, psql.NewCompiler(psql.Config{})
+	defer func() { qcompile, pcompile = qcomp, pcomp }()
+
+	fn(t)
+}
+
+func softDeletedExcluded(t *testing.T) {
+	gql := `query {
+		products {
+			id
+			comments {
+				id
+				body
+			}
+		}
+	}`
+
+	withSoftDelete(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, nil, "user")
+	})
+}
+
+func softDeletedWithArg(t *testing.T) {
+	gql := `query {
+		comments(with_deleted: true) {
+			id
+			body
+		}
+	}`
+
+	withSoftDelete(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, nil, "user")
+	})
+}
+
+func softDeletedWithRole(t *testing.T) {
+	gql := `query {
+		comments {
+			id
+			body
+		}
+	}`
+
+	withSoftDelete(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, nil, "admin")
+	})
+}
+
+func softDeletedArgInvalid(t *testing.T) {
+	gql := `query {
+		products(with_deleted: true) {
+			id
+		}
+	}`
+
+	withSoftDelete(t, func(t *testing.T) {
+		compileGQLToPSQLExpectErr(t, gql, nil, "user")
+	})
+}
+
 func blockedQuery(t *testing.T) {
 	gql := `query {
 		user(id: $id, where: { id: { gt: 3 } }) {
@@ -688,7 +1100,34 @@ func TestCompileQuery(t *testing.T) {
 	t.Run("recursiveTableParents", recursiveTableParents)
 	t.Run("recursiveTableChildren", recursiveTableChildren)
 	t.Run("withCursor", withCursor)
+	t.Run("withConnection", withConnection)
+	t.Run("withNestedConnection", withNestedConnection)
+	t.Run("withConnectionSingular", withConnectionSingular)
+	t.Run("withGlobalIDs", withGlobalIDs)
+	t.Run("withNode", withNode)
+	t.Run("withNodes", withNodes)
+	t.Run("withNodeNoFragments", withNodeNoFragments)
+	t.Run("withAggregate", withAggregate)
+	t.Run("withAggregateLimit", withAggregateLimit)
+	t.Run("withAggregateRoleLimit", withAggregateRoleLimit)
+	t.Run("withAggregateColumn", withAggregateColumn)
+	t.Run("withHaving", withHaving)
+	t.Run("withTimeBucket", withTimeBucket)
+	t.Run("withHavingColumn", withHavingColumn)
+	t.Run("withHavingBlockedColumn", withHavingBlockedColumn)
+	t.Run("withHavingMaskedColumn", withHavingMaskedColumn)
+	t.Run("withMoreAggregates", withMoreAggregates)
+	t.Run("withPercentileNoFraction", withPercentileNoFraction)
+	t.Run("withWindowFunctions", withWindowFunctions)
+	t.Run("withWindowAndAggregate", withWindowAndAggregate)
 	t.Run("nullForAuthRequiredInAnon", nullForAuthRequiredInAnon)
+	t.Run("maskedColumns", maskedColumns)
+	t.Run("maskedFunctions", maskedFunctions)
+	t.Run("maskedFilters", maskedFilters)
+	t.Run("softDeletedExcluded", softDeletedExcluded)
+	t.Run("softDeletedWithArg", softDeletedWithArg)
+	t.Run("softDeletedWithRole", softDeletedWithRole)
+	t.Run("softDeletedArgInvalid", softDeletedArgInvalid)
 	t.Run("blockedQuery", blockedQuery)
 	t.Run("blockedFunctions", blockedFunctions)
 }
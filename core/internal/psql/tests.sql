@@ -10,6 +10,8 @@ WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (price, user_id, created_at, updated_at, name) SELECT (select price from prices where id = $2) :: numeric(7,2), $3 :: bigint, 'now' :: timestamp without time zone, 'now' :: timestamp without time zone, t.name FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t RETURNING *) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (user_id, created_at, updated_at, price, name) SELECT $2 :: bigint, 'now' :: timestamp without time zone, 'now' :: timestamp without time zone, (select price from prices where id = $3) :: numeric(7,2), t.name FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t RETURNING *) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (updated_at, price, user_id, created_at, name) SELECT 'now' :: timestamp without time zone, (select price from prices where id = $2) :: numeric(7,2), $3 :: bigint, 'now' :: timestamp without time zone, t.name FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t RETURNING *) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileInsert/simpleInsertWithCheck
+WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (name, price, user_id) SELECT t.name, t.price, t.user_id FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t RETURNING *) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true WHERE NOT EXISTS (SELECT 1 FROM "products" AS products WHERE (((products.user_id) = $2 :: bigint)) IS NOT TRUE)
 === RUN   TestCompileInsert/nestedInsertManyToMany
 > customer purchases 'customers.id' --(RelOneToMany)--> 'purchases.customer_id'
 >> purchases customer 'purchases.customer_id' --(RelOneToOne)--> 'customers.id'
@@ -2332,6 +2334,7 @@ WITH _sg_input AS (SELECT $1 :: json AS j), "comments_0" AS (INSERT INTO comment
     --- PASS: TestCompileInsert/singleInsert (0.00s)
     --- PASS: TestCompileInsert/bulkInsert (0.00s)
     --- PASS: TestCompileInsert/simpleInsertWithPresets (0.00s)
+    --- PASS: TestCompileInsert/simpleInsertWithCheck (0.00s)
     --- PASS: TestCompileInsert/nestedInsertManyToMany (0.01s)
     --- PASS: TestCompileInsert/nestedInsertOneToMany (0.01s)
     --- PASS: TestCompileInsert/nestedInsertOneToOne (0.01s)
@@ -2347,10 +2350,23 @@ WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (name, description) SELECT t.name, t.description FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t  ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description WHERE ((products.price) > '3' :: numeric(7,2)) RETURNING *) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((products.price) > '3' :: numeric(7,2))) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
 === RUN   TestCompileMutate/delete
 WITH products AS (DELETE FROM products WHERE ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))) AND ((products.id) = '1' :: bigint)) RETURNING products.*) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))) AND ((products.id) = '1' :: bigint))) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileMutate/auditedDelete
+WITH products AS (DELETE FROM products WHERE ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))) AND ((products.id) = '1' :: bigint)) RETURNING products.*) , "_sg_audit" AS (INSERT INTO audit_log ("operation", "query_hash", "role", "user_id", "variables", "tables", "row_ids", "created_at") SELECT $1 :: text, $2 :: text, $3 :: text, $4 :: text, $5 :: jsonb, '["products"]' :: jsonb, jsonb_build_object('products', (SELECT jsonb_agg(products.id) FROM products)), now()) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))) AND ((products.id) = '1' :: bigint))) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileMutate/auditedNestedInsert
+WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (INSERT INTO products (name, price) SELECT t.name, t.price FROM "_sg_input" i, json_populate_record(NULL::"products", i.j->'product') t RETURNING *), "customers" AS (INSERT INTO customers (email) SELECT t.email FROM "_sg_input" i, json_populate_record(NULL::"customers", i.j->'customer') t RETURNING *), "purchases" AS (INSERT INTO purchases (quantity, customer_id, product_id) SELECT t.quantity, customers.id, products.id FROM "_sg_input" i, customers, products, json_populate_record(NULL::"purchases", i.j) t RETURNING *) , "_sg_audit" AS (INSERT INTO audit_log ("operation", "query_hash", "role", "user_id", "variables", "tables", "row_ids", "created_at") SELECT $2 :: text, $3 :: text, $4 :: text, $5 :: text, $6 :: jsonb, '["products", "customers", "purchases"]' :: jsonb, jsonb_build_object('products', (SELECT jsonb_agg(products.id) FROM products), 'customers', (SELECT jsonb_agg(customers.id) FROM customers), 'purchases', (SELECT jsonb_agg(purchases.id) FROM purchases)), now()) SELECT jsonb_build_object('purchase', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT purchases_0.quantity AS quantity, __sj_1.json AS product, __sj_2.json AS customer FROM (SELECT purchases.quantity, purchases.product_id, purchases.customer_id FROM purchases LIMIT 1) AS purchases_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_2.*) AS json FROM (SELECT customers_2.id AS id FROM (SELECT customers.id FROM customers WHERE (((customers.id) = (purchases_0.customer_id))) LIMIT 1) AS customers_2) AS __sr_2) AS __sj_2 ON true LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT products_1.id AS id FROM (SELECT products.id FROM products WHERE (((products.id) = (purchases_0.product_id))) LIMIT 1) AS products_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0 ON true
+WITH _sg_input AS (SELECT $1 :: json AS j), "customers" AS (INSERT INTO customers (email) SELECT t.email FROM "_sg_input" i, json_populate_record(NULL::"customers", i.j->'customer') t RETURNING *), "products" AS (INSERT INTO products (name, price) SELECT t.name, t.price FROM "_sg_input" i, json_populate_record(NULL::"products", i.j->'product') t RETURNING *), "purchases" AS (INSERT INTO purchases (quantity, product_id, customer_id) SELECT t.quantity, products.id, customers.id FROM "_sg_input" i, products, customers, json_populate_record(NULL::"purchases", i.j) t RETURNING *) , "_sg_audit" AS (INSERT INTO audit_log ("operation", "query_hash", "role", "user_id", "variables", "tables", "row_ids", "created_at") SELECT $2 :: text, $3 :: text, $4 :: text, $5 :: text, $6 :: jsonb, '["customers", "products", "purchases"]' :: jsonb, jsonb_build_object('customers', (SELECT jsonb_agg(customers.id) FROM customers), 'products', (SELECT jsonb_agg(products.id) FROM products), 'purchases', (SELECT jsonb_agg(purchases.id) FROM purchases)), now()) SELECT jsonb_build_object('purchase', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT purchases_0.quantity AS quantity, __sj_1.json AS product, __sj_2.json AS customer FROM (SELECT purchases.quantity, purchases.product_id, purchases.customer_id FROM purchases LIMIT 1) AS purchases_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_2.*) AS json FROM (SELECT customers_2.id AS id FROM (SELECT customers.id FROM customers WHERE (((customers.id) = (purchases_0.customer_id))) LIMIT 1) AS customers_2) AS __sr_2) AS __sj_2 ON true LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT products_1.id AS id FROM (SELECT products.id FROM products WHERE (((products.id) = (purchases_0.product_id))) LIMIT 1) AS products_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileMutate/auditedNestedInsertRecursive
+WITH _sg_input AS (SELECT $1 :: json AS j), "comments_0" AS (INSERT INTO comments (id, body) SELECT t.id, t.body FROM "_sg_input" i, json_populate_record(NULL::"comments", i.j) t RETURNING *), "comments_1" AS ( UPDATE comments SET reply_to_id = comments_0.idFROM "_sg_input" i,"comments_0" WHEREcomments.id = ((i.j->'comment'->'connect'->>'id'))::bigint RETURNING comments.*) , comments AS (SELECT * FROM "comments_0" UNION ALL SELECT * FROM "comments_1"), "_sg_audit" AS (INSERT INTO audit_log ("operation", "query_hash", "role", "user_id", "variables", "tables", "row_ids", "created_at") SELECT $2 :: text, $3 :: text, $4 :: text, $5 :: text, $6 :: jsonb, '["comments"]' :: jsonb, jsonb_build_object('comments', (SELECT jsonb_agg(comments.id) FROM (SELECT id FROM "comments_0" UNION ALL SELECT id FROM "comments_1") AS comments)), now()) SELECT jsonb_build_object('comments', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT comments_0.id AS id, __sj_1.json AS comments FROM (SELECT comments.id FROM comments LIMIT 20) AS comments_0 LEFT OUTER JOIN LATERAL (WITH RECURSIVE _rcte_comments AS ((SELECT comments.id, comments.body, comments.reply_to_id FROM comments WHERE (comments.id) = (comments_0.id) LIMIT 1) UNION ALL SELECT comments.id, comments.body, comments.reply_to_id FROM comments, _rcte_comments WHERE ((comments.reply_to_id IS NOT NULL) AND (comments.reply_to_id) != (comments.id) AND (comments.reply_to_id) = (_rcte_comments.id))) SELECT coalesce(jsonb_agg(__sj_1.json), '[]') as json FROM (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT comments_1.id AS id, comments_1.body AS body FROM (SELECT comments.id, comments.body, comments.reply_to_id FROM (SELECT * FROM _rcte_comments OFFSET 1) comments LIMIT 20) AS comments_1) AS __sr_1) AS __sj_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileMutate/softDelete
+WITH comments AS (UPDATE comments SET deleted_at = now() WHERE ((comments.id) = '1' :: bigint) AND (comments.deleted_at IS NULL) RETURNING comments.*) SELECT jsonb_build_object('comments', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT comments_0.id AS id, __sj_1.json AS product FROM (SELECT comments.id, comments.product_id FROM comments WHERE (((comments.id) = '1' :: bigint)) LIMIT 20) AS comments_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT products_1.id AS id FROM (SELECT products.id FROM products WHERE (((products.id) = (comments_0.product_id))) LIMIT 1) AS products_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
 --- PASS: TestCompileMutate (0.01s)
     --- PASS: TestCompileMutate/singleUpsert (0.00s)
     --- PASS: TestCompileMutate/singleUpsertWhere (0.00s)
     --- PASS: TestCompileMutate/delete (0.00s)
+    --- PASS: TestCompileMutate/auditedDelete (0.00s)
+    --- PASS: TestCompileMutate/auditedNestedInsert (0.00s)
+    --- PASS: TestCompileMutate/auditedNestedInsertRecursive (0.00s)
+    --- PASS: TestCompileMutate/softDelete (0.00s)
 === RUN   TestCompileQuery
 === RUN   TestCompileQuery/simpleQuery
 SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, __sj_1.json AS user FROM (SELECT products.id, products.user_id FROM products WHERE ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))) LIMIT 1) AS products_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT users_1.id AS id FROM (SELECT users.id FROM users WHERE (((users.id) = (products_0.user_id))) LIMIT 1) AS users_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0 ON true
@@ -2420,8 +2436,49 @@ SELECT jsonb_build_object('reply', __sj_0.json) AS __root FROM (VALUES(true)) AS
 SELECT jsonb_build_object('comment', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT comments_0.id AS id, __sj_1.json AS replies FROM (SELECT comments.id FROM comments WHERE (((comments.id) = $1 :: bigint)) LIMIT 1) AS comments_0 LEFT OUTER JOIN LATERAL (WITH RECURSIVE _rcte_comments AS ((SELECT comments.id, comments.reply_to_id FROM comments WHERE (comments.id) = (comments_0.id) LIMIT 1) UNION ALL SELECT comments.id, comments.reply_to_id FROM comments, _rcte_comments WHERE ((comments.reply_to_id IS NOT NULL) AND (comments.reply_to_id) != (comments.id) AND (comments.reply_to_id) = (_rcte_comments.id))) SELECT coalesce(jsonb_agg(__sj_1.json), '[]') as json FROM (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT comments_1.id AS id FROM (SELECT comments.id, comments.reply_to_id FROM (SELECT * FROM _rcte_comments OFFSET 1) comments LIMIT 20) AS comments_1) AS __sr_1) AS __sj_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0 ON true
 === RUN   TestCompileQuery/withCursor
 SELECT jsonb_build_object('products', __sj_0.json, 'products_cursor', __sj_0.__cursor) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json, CONCAT_WS(',', max(__cur_0), max(__cur_1)) as __cursor FROM (SELECT to_jsonb(__sr_0.*) - '__cur_0' - '__cur_1' AS json , __cur_0 , __cur_1 FROM (SELECT products_0.name AS name, LAST_VALUE(products_0.price) OVER() AS __cur_0, LAST_VALUE(products_0.id) OVER() AS __cur_1 FROM (WITH __cur AS (SELECT a[1] :: numeric(7,2) as price, a[2] :: bigint as id FROM string_to_array($1, ',') as a) SELECT products.name, products.price, products.id FROM products, __cur WHERE (((((__cur.price) IS NULL) OR ((products.price) < __cur.price :: numeric(7,2)) OR (((products.price) = __cur.price :: numeric(7,2)) AND ((products.id) > __cur.id :: bigint))) AND (((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))))) ORDER BY products.price DESC, products.id ASC LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withConnection
+SELECT jsonb_build_object('products_connection', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT jsonb_build_object('totalCount', (SELECT count(*) FROM (WITH __cur AS (SELECT a[1] :: numeric(7,2) as price, a[2] :: bigint as id FROM string_to_array($1, ',') as a) SELECT products.id, products.name, products.price FROM products, __cur WHERE ((true AND (((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))))) AS __cnt), 'pageInfo', jsonb_build_object('hasNextPage', (count(*) > 20), 'hasPreviousPage', ($1 IS NOT NULL), 'startCursor', (array_agg(__sj_0.__cursor ORDER BY __sj_0.__rn))[1], 'endCursor', (array_agg(__sj_0.__cursor ORDER BY __sj_0.__rn DESC) FILTER (WHERE __sj_0.__rn <= 20))[1]), 'edges', coalesce(jsonb_agg(jsonb_build_object('cursor', __sj_0.__cursor, 'node', __sj_0.json) ORDER BY __sj_0.__rn) FILTER (WHERE __sj_0.__rn <= 20), '[]')) as json FROM (SELECT to_jsonb(__sr_0.*) - '__cursor' - '__rn' AS json , __cursor, __rn FROM (SELECT products_0.id AS id, products_0.name AS name, CONCAT_WS(',', products_0.price, products_0.id) AS __cursor, row_number() OVER() AS __rn FROM (WITH __cur AS (SELECT a[1] :: numeric(7,2) as price, a[2] :: bigint as id FROM string_to_array($1, ',') as a) SELECT products.id, products.name, products.price FROM products, __cur WHERE (((((__cur.price) IS NULL) OR ((products.price) < __cur.price :: numeric(7,2)) OR (((products.price) = __cur.price :: numeric(7,2)) AND ((products.id) > __cur.id :: bigint))) AND (((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))))) ORDER BY products.price DESC, products.id ASC LIMIT 20 + 1) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withNestedConnection
+SELECT jsonb_build_object('users', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT users_0.id AS id, __sj_1.json AS orders FROM (SELECT users.id FROM users LIMIT 20) AS users_0 LEFT OUTER JOIN LATERAL (SELECT jsonb_build_object('__typename', 'productConnection', 'edges', coalesce(jsonb_agg(jsonb_build_object('node', __sj_1.json) ORDER BY __sj_1.__rn) FILTER (WHERE __sj_1.__rn <= LEAST($1, 20)), '[]'), 'pageInfo', jsonb_build_object('next', (count(*) > LEAST($1, 20)))) as json FROM (SELECT to_jsonb(__sr_1.*) - '__cursor' - '__rn' AS json , __cursor, __rn FROM (SELECT products_1.id AS id, products_1.name AS name, CONCAT_WS(',', products_1.id) AS __cursor, row_number() OVER() AS __rn FROM (WITH __cur AS (SELECT a[1] :: bigint as id FROM string_to_array($2, ',') as a) SELECT products.id, products.name FROM products, __cur WHERE (((products.user_id) = (users_0.id)) AND (((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))) ORDER BY products.id ASC LIMIT LEAST($1, 20) + 1) AS products_1) AS __sr_1) AS __sj_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withConnectionSingular
+=== RUN   TestCompileQuery/withGlobalIDs
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT ('products:' || products_0.id :: text) AS id, products_0.name AS name, __sj_1.json AS user FROM (SELECT products.id, products.name, products.user_id FROM products LIMIT 20) AS products_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT ('users:' || users_1.id :: text) AS id, users_1.email AS email FROM (SELECT users.id, users.email FROM users WHERE (((users.id) = (products_0.user_id))) LIMIT 1) AS users_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withNode
+SELECT jsonb_build_object('node', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(__sj_1.json, __sj_2.json) AS json FROM (VALUES($1 :: text)) AS __node_0(id) LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_2.*) AS json FROM (SELECT ('products:' || products_2.id :: text) AS id, products_2.name AS name, products_2.price AS price, ('products' :: text) AS "__typename" FROM (SELECT products.id, products.name, products.price FROM products WHERE (((products.id) = (CASE WHEN left(__node_0.id, 9) = 'products:' THEN substr(__node_0.id, 10) END) :: bigint)) LIMIT 1) AS products_2) AS __sr_2) AS __sj_2 ON true LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT ('users:' || users_1.id :: text) AS id, users_1.email AS email, ('users' :: text) AS "__typename" FROM (SELECT users.id, users.email FROM users WHERE (((users.id) = (CASE WHEN left(__node_0.id, 6) = 'users:' THEN substr(__node_0.id, 7) END) :: bigint)) LIMIT 1) AS users_1) AS __sr_1) AS __sj_1 ON true) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withNodes
+SELECT jsonb_build_object('nodes', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(coalesce(__sj_1.json) ORDER BY __node_0.n), '[]') AS json FROM json_array_elements_text($1) WITH ORDINALITY AS __node_0(id, n) LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT ('products:' || products_1.id :: text) AS id, products_1.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((products.id) = (CASE WHEN left(__node_0.id, 9) = 'products:' THEN substr(__node_0.id, 10) END) :: bigint)) LIMIT 1) AS products_1) AS __sr_1) AS __sj_1 ON true) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withNodeNoFragments
+=== RUN   TestCompileQuery/withAggregate
+SELECT jsonb_build_object('users', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT users_0.id AS id, __sj_1.json AS products, __sj_2.json AS products_aggregate FROM (SELECT users.id FROM users LIMIT 20) AS users_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_2.*) AS json FROM (SELECT products_2.count AS count, products_2.total AS total, products_2.max_price AS max_price FROM (SELECT count(*) AS count, sum(products.price) AS total, max(products.price) AS max_price FROM products WHERE (((products.user_id) = (users_0.id)) AND ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2))) AND ((products.price) > '10' :: numeric(7,2))))) AS products_2) AS __sr_2) AS __sj_2 ON true LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_1.json), '[]') as json FROM (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT products_1.id AS id, products_1.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((products.user_id) = (users_0.id)) AND (((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))) LIMIT 20) AS products_1) AS __sr_1) AS __sj_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withAggregateLimit
+SELECT jsonb_build_object('products_aggregate', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.count AS count, products_0.avg_price AS avg_price FROM (SELECT count(*) AS count, avg(products.price) AS avg_price FROM (SELECT products.* FROM products WHERE ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))) ORDER BY products.price DESC LIMIT 5) AS products) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withAggregateRoleLimit
+SELECT jsonb_build_object('products_aggregate', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.count AS count, products_0.avg_price AS avg_price FROM (SELECT count(*) AS count, avg(products.price) AS avg_price FROM (SELECT products.* FROM products LIMIT 10) AS products) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withAggregateColumn
+=== RUN   TestCompileQuery/withHaving
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.name AS name, products_0.count_id AS count_id, products_0.sum_price AS sum_price FROM (SELECT products.name, count(products.id) AS count_id, sum(products.price) AS sum_price FROM products WHERE ((((products.price) > '0' :: numeric(7,2)) AND ((products.price) < '8' :: numeric(7,2)))) GROUP BY products.name HAVING ((((sum(products.price)) >= '100' :: numeric(7,2)) AND ((count(products.id)) > '2' :: bigint))) LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withTimeBucket
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.month AS month, products_0.count_id AS count_id, products_0.avg_price AS avg_price FROM (SELECT date_trunc('month', products.created_at) AS month, count(products.id) AS count_id, avg(products.price) AS avg_price FROM products GROUP BY date_trunc('month', products.created_at) HAVING (((count(*)) > '1' :: bigint)) LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withHavingColumn
+=== RUN   TestCompileQuery/withMoreAggregates
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.count_distinct_user_id AS count_distinct_user_id, products_0.median AS median, products_0.percentile_disc_price AS percentile_disc_price, products_0.mode_price AS mode_price, products_0.array_agg_id AS array_agg_id, products_0.string_agg_name AS string_agg_name FROM (SELECT count(DISTINCT products.user_id) AS count_distinct_user_id, percentile_cont(0.5) WITHIN GROUP (ORDER BY products.price) AS median, percentile_disc(0.9) WITHIN GROUP (ORDER BY products.price) AS percentile_disc_price, mode() WITHIN GROUP (ORDER BY products.price) AS mode_price, array_agg(products.id) AS array_agg_id, string_agg(products.name :: text, ', ') AS string_agg_name FROM products LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withPercentileNoFraction
+=== RUN   TestCompileQuery/withWindowFunctions
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.rank AS rank, products_0.row_number AS row_number, products_0.prev_price AS prev_price, products_0.sum_over_price AS sum_over_price FROM (SELECT products.id, products.price, rank() OVER (ORDER BY products.price DESC) AS rank, row_number() OVER (PARTITION BY products.user_id ORDER BY products.id ASC) AS row_number, lag(products.price, 2) OVER (ORDER BY products.id ASC) AS prev_price, sum(products.price) OVER (PARTITION BY products.user_id ORDER BY products.id ASC) AS sum_over_price FROM products ORDER BY products.price DESC LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/withWindowAndAggregate
 === RUN   TestCompileQuery/nullForAuthRequiredInAnon
 SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name, NULL AS user FROM (SELECT products.id, products.name, products.user_id FROM products LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/maskedColumns
+SELECT jsonb_build_object('users', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT users_0.id AS id, encode(hmac(users_0.full_name::text, $1 :: text, 'sha256'), 'hex') AS full_name, (repeat('*', greatest(length(users_0.phone::text) - 4, 0)) || right(users_0.phone::text, 4)) AS phone, (left(users_0.email::text, 1) || '***@' || split_part(users_0.email::text, '@', 2)) AS email, NULL AS encrypted_password FROM (SELECT users.id, users.full_name, users.phone, users.email, users.encrypted_password FROM users LIMIT 20) AS users_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/maskedFunctions
+=== RUN   TestCompileQuery/maskedFilters
+=== RUN   TestCompileQuery/softDeletedExcluded
+SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, __sj_1.json AS comments FROM (SELECT products.id FROM products LIMIT 20) AS products_0 LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_1.json), '[]') as json FROM (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT comments_1.id AS id, comments_1.body AS body FROM (SELECT comments.id, comments.body FROM comments WHERE (((comments.product_id) = (products_0.id)) AND ((comments.deleted_at) IS NULL)) LIMIT 20) AS comments_1) AS __sr_1) AS __sj_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/softDeletedWithArg
+SELECT jsonb_build_object('comments', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT comments_0.id AS id, comments_0.body AS body FROM (SELECT comments.id, comments.body FROM comments LIMIT 20) AS comments_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/softDeletedWithRole
+SELECT jsonb_build_object('comments', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT comments_0.id AS id, comments_0.body AS body FROM (SELECT comments.id, comments.body FROM comments LIMIT 20) AS comments_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true
+=== RUN   TestCompileQuery/softDeletedArgInvalid
 === RUN   TestCompileQuery/blockedQuery
 SELECT jsonb_build_object('user', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT users_0.id AS id, users_0.full_name AS full_name, users_0.email AS email FROM (SELECT users.id, users.full_name, users.email FROM users WHERE (false) LIMIT 1) AS users_0) AS __sr_0) AS __sj_0 ON true
 === RUN   TestCompileQuery/blockedFunctions
@@ -2461,7 +2518,32 @@ SELECT jsonb_build_object('user', __sj_0.json) AS __root FROM (VALUES(true)) AS
     --- PASS: TestCompileQuery/recursiveTableParents (0.00s)
     --- PASS: TestCompileQuery/recursiveTableChildren (0.00s)
     --- PASS: TestCompileQuery/withCursor (0.00s)
+    --- PASS: TestCompileQuery/withConnection (0.00s)
+    --- PASS: TestCompileQuery/withNestedConnection (0.00s)
+    --- PASS: TestCompileQuery/withConnectionSingular (0.00s)
+    --- PASS: TestCompileQuery/withGlobalIDs (0.00s)
+    --- PASS: TestCompileQuery/withNode (0.00s)
+    --- PASS: TestCompileQuery/withNodes (0.00s)
+    --- PASS: TestCompileQuery/withNodeNoFragments (0.00s)
+    --- PASS: TestCompileQuery/withAggregate (0.00s)
+    --- PASS: TestCompileQuery/withAggregateLimit (0.00s)
+    --- PASS: TestCompileQuery/withAggregateRoleLimit (0.00s)
+    --- PASS: TestCompileQuery/withAggregateColumn (0.00s)
+    --- PASS: TestCompileQuery/withHaving (0.00s)
+    --- PASS: TestCompileQuery/withTimeBucket (0.00s)
+    --- PASS: TestCompileQuery/withHavingColumn (0.00s)
+    --- PASS: TestCompileQuery/withMoreAggregates (0.00s)
+    --- PASS: TestCompileQuery/withPercentileNoFraction (0.00s)
+    --- PASS: TestCompileQuery/withWindowFunctions (0.00s)
+    --- PASS: TestCompileQuery/withWindowAndAggregate (0.00s)
     --- PASS: TestCompileQuery/nullForAuthRequiredInAnon (0.00s)
+    --- PASS: TestCompileQuery/maskedColumns (0.00s)
+    --- PASS: TestCompileQuery/maskedFunctions (0.00s)
+    --- PASS: TestCompileQuery/maskedFilters (0.00s)
+    --- PASS: TestCompileQuery/softDeletedExcluded (0.00s)
+    --- PASS: TestCompileQuery/softDeletedWithArg (0.00s)
+    --- PASS: TestCompileQuery/softDeletedWithRole (0.00s)
+    --- PASS: TestCompileQuery/softDeletedArgInvalid (0.00s)
     --- PASS: TestCompileQuery/blockedQuery (0.00s)
     --- PASS: TestCompileQuery/blockedFunctions (0.00s)
 === RUN   TestCompileUpdate
@@ -2469,6 +2551,8 @@ SELECT jsonb_build_object('user', __sj_0.json) AS __root FROM (VALUES(true)) AS
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (UPDATE products SET (name, description) = (SELECT t.name, t.description FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t) WHERE (((products.id) = '1' :: bigint) AND ((products.id) = $2 :: bigint)) RETURNING products.*) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name FROM (SELECT products.id, products.name FROM products WHERE ((((products.id) = '1' :: bigint) AND ((products.id) = $2 :: bigint))) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
 === RUN   TestCompileUpdate/simpleUpdateWithPresets
 WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (UPDATE products SET (updated_at, name, price) = (SELECT 'now' :: timestamp without time zone, t.name, t.price FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t) WHERE ((products.user_id) = $2 :: bigint) RETURNING products.*) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products WHERE (((products.user_id) = $2 :: bigint)) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true
+=== RUN   TestCompileUpdate/simpleUpdateWithCheck
+WITH _sg_input AS (SELECT $1 :: json AS j), "products" AS (UPDATE products SET (name, price) = (SELECT t.name, t.price FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t) WHERE ((products.user_id) = $2 :: bigint) RETURNING products.*) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id FROM (SELECT products.id FROM products WHERE (((products.user_id) = $2 :: bigint)) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true WHERE NOT EXISTS (SELECT 1 FROM "products" AS products WHERE (((products.price) > '0' :: numeric(7,2))) IS NOT TRUE)
 === RUN   TestCompileUpdate/nestedUpdateManyToMany
 > customer purchases 'customers.id' --(RelOneToMany)--> 'purchases.customer_id'
 >> purchases customer 'purchases.customer_id' --(RelOneToOne)--> 'customers.id'
@@ -4588,9 +4672,17 @@ WITH _sg_input AS (SELECT $1 :: json AS j), "comments_0" AS (UPDATE comments SET
 > comment comments 'comments.reply_to_id' --(RelRecursive)--> 'comments.id'
 >> comments comment 'comments.reply_to_id' --(RelRecursive)--> 'comments.id'
 > connect comment '.' --(RelNone)--> '.'
+=== RUN   TestCompileUpdate/versionedUpdate
+WITH _sg_input AS (SELECT $1 :: json AS j), "_sg_version_products" AS (SELECT count(*) AS n FROM products WHERE ((products.id) = $2 :: bigint)), "products" AS (UPDATE products SET (name, updated_at) = (SELECT t.name, now() FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t) WHERE ((products.id) = $2 :: bigint) AND ((products.updated_at) = (SELECT t.updated_at FROM "_sg_input" i, json_populate_record(NULL::"products", i.j) t)) RETURNING products.*) SELECT jsonb_build_object('product', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name, products_0.updated_at AS updated_at FROM (SELECT products.id, products.name, products.updated_at FROM products WHERE (((products.id) = $2 :: bigint)) LIMIT 1) AS products_0) AS __sr_0) AS __sj_0 ON true WHERE ((SELECT count(*) FROM "products") = (SELECT n FROM "_sg_version_products"))
+=== RUN   TestCompileUpdate/versionedNestedUpdate
+WITH _sg_input AS (SELECT $1 :: json AS j), "users" AS (UPDATE users SET (full_name) = (SELECT t.full_name FROM "_sg_input" i, json_populate_record(NULL::"users", i.j) t) WHERE ((users.id) = '8' :: bigint) RETURNING users.*), "_sg_version_products" AS (SELECT count(*) AS n FROM products, "_sg_input" i, users WHERE ((products.user_id) = (users.id) AND products.id = ((i.j->'product'->'where'->>'id'))::bigint)), "products" AS (UPDATE products SET (updated_at) = (SELECT now() FROM "_sg_input" i, json_populate_record(NULL::"products", i.j->'product') t) FROM "_sg_input" i, users WHERE ((products.user_id) = (users.id) AND products.id = ((i.j->'product'->'where'->>'id'))::bigint AND ((products.updated_at) = (SELECT t.updated_at FROM "_sg_input" i, json_populate_record(NULL::"products", i.j->'product') t))) RETURNING products.*) SELECT jsonb_build_object('user', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT users_0.id AS id, users_0.full_name AS full_name, __sj_1.json AS product FROM (SELECT users.id, users.full_name FROM users WHERE (((users.id) = '8' :: bigint)) LIMIT 1) AS users_0 LEFT OUTER JOIN LATERAL (SELECT to_jsonb(__sr_1.*) AS json FROM (SELECT products_1.id AS id, products_1.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((products.user_id) = (users_0.id))) LIMIT 1) AS products_1) AS __sr_1) AS __sj_1 ON true) AS __sr_0) AS __sj_0 ON true WHERE ((SELECT count(*) FROM "products") = (SELECT n FROM "_sg_version_products"))
+=== RUN   TestCompileUpdate/versionedBulkUpdate
+WITH _sg_input AS (SELECT $1 :: json AS j), "_sg_version_products" AS (SELECT count(*) AS n FROM products WHERE ((products.id) = ANY (ARRAY(SELECT json_array_elements_text($2)) :: bigint[]))), "products" AS (UPDATE products SET (name, updated_at) = (SELECT t.name, now() FROM "_sg_input" i, json_populate_recordset(NULL::"products", i.j) t) WHERE ((products.id) = ANY (ARRAY(SELECT json_array_elements_text($2)) :: bigint[])) AND ((products.updated_at) IN (SELECT t.updated_at FROM "_sg_input" i, json_populate_recordset(NULL::"products", i.j) t)) RETURNING products.*) SELECT jsonb_build_object('products', __sj_0.json) AS __root FROM (VALUES(true)) AS __root_x LEFT OUTER JOIN LATERAL (SELECT coalesce(jsonb_agg(__sj_0.json), '[]') as json FROM (SELECT to_jsonb(__sr_0.*) AS json FROM (SELECT products_0.id AS id, products_0.name AS name FROM (SELECT products.id, products.name FROM products WHERE (((products.id) = ANY (ARRAY(SELECT json_array_elements_text($2)) :: bigint[]))) LIMIT 20) AS products_0) AS __sr_0) AS __sj_0) AS __sj_0 ON true WHERE ((SELECT count(*) FROM "products") = (SELECT count(*) FROM "_sg_input" i, json_populate_recordset(NULL::"products", i.j) t) OR (SELECT n FROM "_sg_version_products") = 0)
+=== RUN   TestCompileUpdate/versionedUpdateWithoutVersion
 --- PASS: TestCompileUpdate (0.05s)
     --- PASS: TestCompileUpdate/singleUpdate (0.00s)
     --- PASS: TestCompileUpdate/simpleUpdateWithPresets (0.00s)
+    --- PASS: TestCompileUpdate/simpleUpdateWithCheck (0.00s)
     --- PASS: TestCompileUpdate/nestedUpdateManyToMany (0.01s)
     --- PASS: TestCompileUpdate/nestedUpdateOneToMany (0.01s)
     --- PASS: TestCompileUpdate/nestedUpdateOneToOne (0.01s)
@@ -4598,5 +4690,9 @@ WITH _sg_input AS (SELECT $1 :: json AS j), "comments_0" AS (UPDATE comments SET
     --- PASS: TestCompileUpdate/nestedUpdateOneToOneWithConnect (0.01s)
     --- PASS: TestCompileUpdate/nestedUpdateOneToOneWithDisconnect (0.01s)
     --- PASS: TestCompileUpdate/nestedUpdateRecursive (0.01s)
+    --- PASS: TestCompileUpdate/versionedUpdate (0.00s)
+    --- PASS: TestCompileUpdate/versionedNestedUpdate (0.00s)
+    --- PASS: TestCompileUpdate/versionedBulkUpdate (0.00s)
+    --- PASS: TestCompileUpdate/versionedUpdateWithoutVersion (0.00s)
 PASS
 ok  	github.com/dosco/graphjin/core/internal/psql	0.409s
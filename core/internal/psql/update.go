@@ -24,6 +24,8 @@ func (c *compilerContext) renderUpdate() {
 }
 
 func (c *compilerContext) renderUpdateStmt(m qcode.Mutate) {
+	c.renderVersionTarget(m)
+
 	c.w.WriteString(`, `)
 	if m.Multi {
 		renderCteNameWithSuffix(c.w, m, strconv.Itoa(int(m.MID)))
@@ -38,14 +40,113 @@ func (c *compilerContext) renderUpdateStmt(m qcode.Mutate) {
 	c.w.WriteString(` SET (`)
 	n := c.renderInsertUpdateColumns(m, false)
 	c.renderNestedInsertUpdateRelColumns(m, true, n)
+	c.renderVersionColumn(m, false, n+len(m.RCols))
 
 	c.w.WriteString(`) = (SELECT `)
 	n = c.renderInsertUpdateColumns(m, true)
 	c.renderNestedInsertUpdateRelColumns(m, true, n)
+	c.renderVersionColumn(m, true, n+len(m.RCols))
 
 	c.w.WriteString(` FROM "_sg_input" i`)
 	c.renderNestedInsertUpdateRelTables(m)
+	c.renderInputRecord(m)
+
+	if len(m.Path) == 0 {
+		c.w.WriteString(`)`)
+	} else {
+		c.w.WriteString(`) `)
+	}
+
+	if m.ID == 0 {
+		c.w.WriteString(` WHERE `)
+		c.renderExp(c.qc.Schema, m.Ti, c.qc.Selects[0].Where.Exp, false)
+		c.renderVersionCheck(m)
+	} else {
+		// Render sql to set id values if child-to-parent
+		// relationship is one-to-one
+		c.w.WriteString(`FROM `)
+		c.renderUpdateRelTables(m)
+		c.w.WriteString(` WHERE (`)
+		c.renderUpdateRelWhere(m)
+		c.renderVersionCheck(m)
+		c.w.WriteString(`)`)
+	}
+
+	c.w.WriteString(` RETURNING `)
+	quoted(c.w, m.Ti.Name)
+	c.w.WriteString(`.*)`)
+}
+
+// renderVersionTarget counts the rows an update of a versioned table
+// would match without the version check. It's rendered before the update
+// so the table name refers to the table and not the update.
+func (c *compilerContext) renderVersionTarget(m qcode.Mutate) {
+	if m.Ti.VersionCol.Name == "" {
+		return
+	}
+	c.w.WriteString(`, `)
+	c.renderVersionCteName(m)
+	c.w.WriteString(` AS (SELECT count(*) AS n FROM `)
+
+	if m.ID == 0 {
+		quoted(c.w, m.Ti.Name)
+		c.w.WriteString(` WHERE `)
+		c.renderExp(c.qc.Schema, m.Ti, c.qc.Selects[0].Where.Exp, false)
+	} else {
+		quoted(c.w, m.Ti.Name)
+		c.w.WriteString(`, `)
+		c.renderUpdateRelTables(m)
+		c.w.WriteString(` WHERE (`)
+		c.renderUpdateRelWhere(m)
+		c.w.WriteString(`)`)
+	}
+	c.w.WriteString(`)`)
+}
 
+func (c *compilerContext) renderVersionCteName(m qcode.Mutate) {
+	c.w.WriteString(`"_sg_version_`)
+	c.w.WriteString(m.Ti.Name)
+	if m.Multi {
+		c.w.WriteString(`_`)
+		c.w.WriteString(strconv.Itoa(int(m.MID)))
+	}
+	c.w.WriteString(`"`)
+}
+
+// renderUpdateRelTables renders the parent table of a nested update
+// and the input when it's needed by the where clause of a versioned update
+func (c *compilerContext) renderUpdateRelTables(m qcode.Mutate) {
+	if hasUpdateWhere(m) && m.Ti.VersionCol.Name != "" {
+		c.w.WriteString(`"_sg_input" i, `)
+	}
+	quoted(c.w, m.RelCP.Right.Col.Table)
+}
+
+// renderUpdateRelWhere renders the condition matching the rows of a
+// nested update to its parent and the where clause in the input
+func (c *compilerContext) renderUpdateRelWhere(m qcode.Mutate) {
+	rel := m.RelCP
+
+	c.w.WriteString(`(`)
+	colWithTable(c.w, rel.Left.Col.Table, rel.Left.Col.Name)
+	c.w.WriteString(`) = (`)
+	colWithTable(c.w, rel.Right.Col.Table, rel.Right.Col.Name)
+	c.w.WriteString(`)`)
+
+	if hasUpdateWhere(m) {
+		c.w.WriteString(` AND `)
+		c.renderWhereFromJSON(m, "where")
+	}
+}
+
+func hasUpdateWhere(m qcode.Mutate) bool {
+	_, ok := m.Data["where"]
+	return ok && m.RelPC.Type == sdata.RelOneToMany
+}
+
+// renderInputRecord renders the rows of the input json used to
+// update the table
+func (c *compilerContext) renderInputRecord(m qcode.Mutate) {
 	if m.Array {
 		c.w.WriteString(`, json_populate_recordset`)
 	} else {
@@ -56,40 +157,55 @@ func (c *compilerContext) renderUpdateStmt(m qcode.Mutate) {
 	c.w.WriteString(m.Ti.Name)
 
 	if len(m.Path) == 0 {
-		c.w.WriteString(`", i.j) t)`)
+		c.w.WriteString(`", i.j) t`)
 	} else {
 		c.w.WriteString(`", i.j->`)
 		joinPath(c.w, m.Path)
-		c.w.WriteString(`) t) `)
+		c.w.WriteString(`) t`)
 	}
+}
 
-	if m.ID == 0 {
-		c.w.WriteString(` WHERE `)
-		c.renderExp(c.qc.Schema, m.Ti, c.qc.Selects[0].Where.Exp, false)
-	} else {
-		// Render sql to set id values if child-to-parent
-		// relationship is one-to-one
-		rel := m.RelCP
-
-		c.w.WriteString(`FROM `)
-		quoted(c.w, rel.Right.Col.Table)
+// renderVersionColumn bumps the version column of the table,
+// integer versions are incremented and timestamps set to now()
+func (c *compilerContext) renderVersionColumn(m qcode.Mutate, values bool, n int) {
+	col := m.Ti.VersionCol
+	if col.Name == "" {
+		return
+	}
+	if n != 0 {
+		c.w.WriteString(`, `)
+	}
 
-		c.w.WriteString(` WHERE ((`)
-		colWithTable(c.w, rel.Left.Col.Table, rel.Left.Col.Name)
-		c.w.WriteString(`) = (`)
-		colWithTable(c.w, rel.Right.Col.Table, rel.Right.Col.Name)
-		c.w.WriteString(`)`)
+	switch {
+	case !values:
+		quoted(c.w, col.Name)
+	case col.IsTimestamp():
+		c.w.WriteString(`now()`)
+	default:
+		colWithTable(c.w, m.Ti.Name, col.Name)
+		c.w.WriteString(` + 1`)
+	}
+}
 
-		if m.RelPC.Type == sdata.RelOneToMany {
-			if _, ok := m.Data["where"]; ok {
-				c.w.WriteString(` AND `)
-				c.renderWhereFromJSON(m, "where")
-			}
-		}
-		c.w.WriteString(`)`)
+// renderVersionCheck only updates the rows whose version column still
+// matches the value in the input, the number of rows updated is then
+// compared by renderChecks
+func (c *compilerContext) renderVersionCheck(m qcode.Mutate) {
+	col := m.Ti.VersionCol
+	if col.Name == "" {
+		return
 	}
 
-	c.w.WriteString(` RETURNING `)
-	quoted(c.w, m.Ti.Name)
-	c.w.WriteString(`.*)`)
+	c.w.WriteString(` AND ((`)
+	colWithTable(c.w, m.Ti.Name, col.Name)
+
+	if m.Array {
+		c.w.WriteString(`) IN (SELECT `)
+	} else {
+		c.w.WriteString(`) = (SELECT `)
+	}
+	colWithTable(c.w, "t", col.Name)
+	c.w.WriteString(` FROM "_sg_input" i`)
+	c.renderInputRecord(m)
+	c.w.WriteString(`))`)
 }
@@ -3,6 +3,10 @@ package psql_test
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/dosco/graphjin/core/internal/psql"
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
 )
 
 func singleUpdate(t *testing.T) {
@@ -272,9 +276,139 @@ func nestedUpdateRecursive(t *testing.T) {
 	compileGQLToPSQL(t, gql, vars, "user")
 }
 
+func simpleUpdateWithCheck(t *testing.T) {
+	gql := `mutation {
+		product(update: $data) {
+			id
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{"name": "Apple", "price": 1.25}`),
+	}
+
+	compileGQLToPSQL(t, gql, vars, "checked_user")
+}
+
+func withVersion(t *testing.T, fn func(t *testing.T)) {
+	di := sdata.GetTestDBInfo()
+
+	col, err := di.GetColumn("products", "updated_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	col.Version = true
+
+	schema, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qc, err := qcode.NewCompiler(schema, qcode.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qcomp, pcomp := qcompile, pcompile
+	qcompile, pcompile = qc, psql.NewCompiler(psql.Config{})
+	defer func() { qcompile, pcompile = qcomp, pcomp }()
+
+	fn(t)
+}
+
+func versionedUpdate(t *testing.T) {
+	gql := `mutation {
+		product(id: $id, update: $data) {
+			id
+			name
+			updated_at
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{
+			"name": "Apple",
+			"updated_at": "2021-01-09T16:37:01.000000"
+		}`),
+	}
+
+	withVersion(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, vars, "admin")
+	})
+}
+
+func versionedNestedUpdate(t *testing.T) {
+	gql := `mutation {
+		user(update: $data, where: { id: { eq: 8 } }) {
+			id
+			full_name
+			product {
+				id
+				name
+			}
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{
+			"full_name": "The Dude",
+			"product": {
+				"where": {
+					"id": 2
+				},
+				"updated_at": "2021-01-09T16:37:01.000000"
+			}
+		}`),
+	}
+
+	withVersion(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, vars, "admin")
+	})
+}
+
+func versionedBulkUpdate(t *testing.T) {
+	gql := `mutation {
+		products(where: { id: { in: $ids } }, update: $data) {
+			id
+			name
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`[{
+			"name": "Apple",
+			"updated_at": "2021-01-09T16:37:01.000000"
+		}, {
+			"name": "Orange",
+			"updated_at": "2021-01-10T16:37:01.000000"
+		}]`),
+	}
+
+	withVersion(t, func(t *testing.T) {
+		compileGQLToPSQL(t, gql, vars, "admin")
+	})
+}
+
+func versionedUpdateWithoutVersion(t *testing.T) {
+	gql := `mutation {
+		product(id: $id, update: $data) {
+			id
+		}
+	}`
+
+	vars := map[string]json.RawMessage{
+		"data": json.RawMessage(`{"name": "Apple"}`),
+	}
+
+	withVersion(t, func(t *testing.T) {
+		compileGQLToPSQLExpectErr(t, gql, vars, "admin")
+	})
+}
+
 func TestCompileUpdate(t *testing.T) {
 	t.Run("singleUpdate", singleUpdate)
 	t.Run("simpleUpdateWithPresets", simpleUpdateWithPresets)
+	t.Run("simpleUpdateWithCheck", simpleUpdateWithCheck)
 	t.Run("nestedUpdateManyToMany", nestedUpdateManyToMany)
 	t.Run("nestedUpdateOneToMany", nestedUpdateOneToMany)
 	t.Run("nestedUpdateOneToOne", nestedUpdateOneToOne)
@@ -283,5 +417,9 @@ func TestCompileUpdate(t *testing.T) {
 	t.Run("nestedUpdateOneToOneWithDisconnect", nestedUpdateOneToOneWithDisconnect)
 	//t.Run("nestedUpdateOneToOneWithDisconnectArray", nestedUpdateOneToOneWithDisconnectArray)
 	t.Run("nestedUpdateRecursive", nestedUpdateRecursive)
+	t.Run("versionedUpdate", versionedUpdate)
+	t.Run("versionedNestedUpdate", versionedNestedUpdate)
+	t.Run("versionedBulkUpdate", versionedBulkUpdate)
+	t.Run("versionedUpdateWithoutVersion", versionedUpdateWithoutVersion)
 
 }
@@ -0,0 +1,101 @@
+package qcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+const aggSuffix = "_aggregate"
+
+func (co *Compiler) isAggregate(field *graph.Field) bool {
+	if !strings.HasSuffix(field.Name, aggSuffix) || len(field.Children) == 0 {
+		return false
+	}
+	// tables named with the suffix are not aggregates
+	_, err := co.s.GetTableInfo(field.Name, "")
+	return err != nil
+}
+
+// compileAggregate checks the arguments and relationship of an aggregate
+// select, it returns a single row so only an explicit limit or the limit
+// of the role is applied
+func (co *Compiler) compileAggregate(qc *QCode, field *graph.Field, sel *Select, tr trval) error {
+	if qc.Type == QTMutation {
+		return fmt.Errorf("aggregates are not supported with mutations: %s", sel.FieldName)
+	}
+	if co.s.Type() == "mysql" {
+		return fmt.Errorf("aggregates are not supported with mysql: %s", sel.FieldName)
+	}
+
+	switch sel.Rel.Type {
+	case sdata.RelNone, sdata.RelOneToOne, sdata.RelOneToMany, sdata.RelOneToManyThrough:
+	default:
+		return fmt.Errorf("aggregates are not supported on this relationship: %s", sel.FieldName)
+	}
+
+	if sel.Paging.Cursor {
+		return fmt.Errorf("aggregates do not support cursor pagination: %s", sel.FieldName)
+	}
+
+	// the default limit is dropped but a role limit is kept
+	sel.Paging.NoLimit = (tr.limit(qc.Type) == 0)
+
+	for _, arg := range field.Args {
+		switch arg.Name {
+		case "limit", "first", "last":
+			sel.Paging.NoLimit = false
+		}
+	}
+
+	sel.Singular = true
+	return nil
+}
+
+// compileAggregateColumns adds the aggregate functions selected,
+// count is the number of rows and the rest use the function prefix
+// for example sum_price
+func (co *Compiler) compileAggregateColumns(
+	field *graph.Field, op *graph.Operation, sel *Select) error {
+
+	for _, cid := range field.Children {
+		var fname string
+		f := op.Fields[cid]
+
+		if f.Alias != "" {
+			fname = f.Alias
+		} else {
+			fname = f.Name
+		}
+
+		if len(f.Children) != 0 {
+			return fmt.Errorf("aggregate: invalid selection for field '%s'", f.Name)
+		}
+
+		if f.Name == "count" {
+			sel.Funcs = append(sel.Funcs, Function{Name: "count", FieldName: fname})
+			continue
+		}
+
+		fn, agg, err := co.isFunction(sel, f.Name)
+		if err != nil {
+			return err
+		}
+		if fn.skip {
+			continue
+		}
+		if !agg {
+			return fmt.Errorf("aggregate: '%s' is not an aggregate function", f.Name)
+		}
+
+		if err := compileFuncArgs(&fn, f.Args); err != nil {
+			return err
+		}
+		fn.FieldName = fname
+		sel.Funcs = append(sel.Funcs, fn)
+	}
+
+	return nil
+}
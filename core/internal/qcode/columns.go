@@ -18,21 +18,29 @@ func (co *Compiler) compileColumns(
 
 	sel.Cols = make([]Column, 0, len(field.Children))
 
-	//if sel.Rel.Type != sdata.RelRemote {
-	if err := co.compileChildColumns(field, op, st, qc, sel, tr); err != nil {
+	var err error
+
+	if sel.Aggregate {
+		err = co.compileAggregateColumns(field, op, sel)
+	} else {
+		err = co.compileChildColumns(field, op, st, qc, sel, tr)
+	}
+	if err != nil {
 		return err
 	}
-	//}
 
-	if err := validateSelector(qc, sel, tr); err != nil {
+	if err = validateSelector(qc, sel, tr); err != nil {
 		return err
 	}
 
-	if err := co.addRelColumns(qc, sel); err != nil {
+	if err = co.addRelColumns(qc, sel); err != nil {
 		return err
 	}
 
-	co.addOrderByColumns(sel)
+	// the rows of an aggregate are ordered in a subquery
+	if !sel.Aggregate {
+		co.addOrderByColumns(sel)
+	}
 	return nil
 }
 
@@ -45,6 +53,8 @@ func (co *Compiler) compileChildColumns(
 	tr trval) error {
 
 	aggExist := false
+	bucketExist := false
+	windowExist := false
 
 	for _, cid := range field.Children {
 		var fname string
@@ -77,7 +87,9 @@ func (co *Compiler) compileChildColumns(
 
 		// not a function
 		if fn.Name == "" {
-			if dbc, err := sel.Ti.GetColumnB(f.Name); err == nil {
+			if co.isGlobalID(sel, f.Name) {
+				sel.addCol(Column{Col: sel.Ti.PrimaryCol, FieldName: fname, GlobalID: true})
+			} else if dbc, err := sel.Ti.GetColumnB(f.Name); err == nil {
 				sel.addCol(Column{Col: dbc, FieldName: fname})
 			} else {
 				return err
@@ -87,12 +99,30 @@ func (co *Compiler) compileChildColumns(
 			if agg {
 				aggExist = true
 			}
+			if fn.Name == "date_trunc" {
+				bucketExist = true
+			}
+			if fn.Window != nil {
+				windowExist = true
+				err = co.compileWindowArgs(sel.Ti, &fn, f.Args, tr)
+			} else {
+				err = compileFuncArgs(&fn, f.Args)
+			}
+			if err != nil {
+				return err
+			}
 			fn.FieldName = fname
 			sel.Funcs = append(sel.Funcs, fn)
 		}
 	}
 
-	if aggExist && len(sel.Cols) != 0 {
+	if windowExist && (aggExist || sel.Having.Exp != nil) {
+		return fmt.Errorf("window functions cannot be used with aggregates: %s", sel.FieldName)
+	}
+
+	// rows are grouped by the columns and time buckets selected
+	// along with aggregates or when filtered using having
+	if (aggExist || sel.Having.Exp != nil) && (len(sel.Cols) != 0 || bucketExist) {
 		sel.GroupCols = true
 	}
 
@@ -167,10 +197,11 @@ func (co *Compiler) orderByIDCol(sel *Select) error {
 }
 
 func validateSelector(qc *QCode, sel *Select, tr trval) error {
-	for _, col := range sel.Cols {
+	for i, col := range sel.Cols {
 		if !tr.columnAllowed(qc, col.Col.Name) {
 			return fmt.Errorf("column blocked: %s (%s)", col.Col.Name, tr.role)
 		}
+		sel.Cols[i].Mask = tr.mask(col.Col.Name)
 
 		// if _, ok := sel.ColMap[col.FieldName]; ok {
 		// 	return fmt.Errorf("duplicate field: %s", col.FieldName)
@@ -195,9 +226,10 @@ func validateSelector(qc *QCode, sel *Select, tr trval) error {
 
 		if fn.Col.Name != "" {
 			blocked = !tr.columnAllowed(qc, fn.Col.Name)
-			fnID = (fn.Name + fn.Col.Name)
+			fnID = (fn.Name + fn.Arg + fn.Col.Name)
 		} else {
-			blocked = !tr.columnAllowed(qc, fn.Name)
+			// counting or ranking rows needs no column
+			blocked = fn.Name != "count" && fn.Window == nil && !tr.columnAllowed(qc, fn.Name)
 			fnID = fn.Name
 		}
 
@@ -205,6 +237,11 @@ func validateSelector(qc *QCode, sel *Select, tr trval) error {
 			return fmt.Errorf("column blocked: %s (%s)", fn.Name, tr.role)
 		}
 
+		// functions over masked columns would leak the unmasked value
+		if fn.Col.Name != "" && tr.mask(fn.Col.Name) != MaskTypeNone {
+			return fmt.Errorf("column masked: %s (%s)", fn.Col.Name, tr.role)
+		}
+
 		if fn.FieldName != "" {
 			if _, ok := sel.ColMap[fn.FieldName]; ok {
 				return fmt.Errorf("duplicate field: %s", fn.FieldName)
@@ -212,7 +249,8 @@ func validateSelector(qc *QCode, sel *Select, tr trval) error {
 			sel.ColMap[fn.FieldName] = -1
 		}
 
-		if fn.FieldName != fnID {
+		// window functions over the same column can differ in the window
+		if fn.FieldName != fnID && fn.Window == nil {
 			if _, ok := sel.ColMap[fnID]; ok {
 				return fmt.Errorf("duplicate function: %s(%s)", fn.Name, fn.Col.Name)
 			}
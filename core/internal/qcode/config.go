@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/dosco/graphjin/core/internal/sdata"
 	"github.com/gobuffalo/flect"
 )
 
@@ -12,6 +13,7 @@ type Config struct {
 	FragmentFetcher func(name string) (string, error)
 	DefaultBlock    bool
 	DefaultLimit    int
+	GlobalIDs       bool
 	defTrv          trval
 }
 
@@ -27,12 +29,15 @@ type QueryConfig struct {
 	Limit            int
 	Filters          []string
 	Columns          []string
+	Masks            map[string]string
 	DisableFunctions bool
+	WithDeleted      bool
 	Block            bool
 }
 
 type InsertConfig struct {
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -40,6 +45,7 @@ type InsertConfig struct {
 type UpdateConfig struct {
 	Filters []string
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -47,6 +53,7 @@ type UpdateConfig struct {
 type UpsertConfig struct {
 	Filters []string
 	Columns []string
+	Check   []string
 	Presets map[string]string
 	Block   bool
 }
@@ -65,12 +72,15 @@ type trval struct {
 		fil     *Exp
 		filNU   bool
 		cols    map[string]struct{}
+		masks   map[string]MaskType
 		disable struct{ funcs bool }
+		deleted bool
 		block   bool
 	}
 
 	insert struct {
 		cols    map[string]struct{}
+		check   *Exp
 		presets map[string]string
 		block   bool
 	}
@@ -79,6 +89,7 @@ type trval struct {
 		fil     *Exp
 		filNU   bool
 		cols    map[string]struct{}
+		check   *Exp
 		presets map[string]string
 		block   bool
 	}
@@ -87,6 +98,7 @@ type trval struct {
 		fil     *Exp
 		filNU   bool
 		cols    map[string]struct{}
+		check   *Exp
 		presets map[string]string
 		block   bool
 	}
@@ -119,11 +131,21 @@ func (co *Compiler) AddRole(role, table string, trc TRConfig) error {
 		trv.query.limit = int32(trc.Query.Limit)
 	}
 	trv.query.cols = makeSet(trc.Query.Columns)
+	if trv.query.masks, err = compileMasks(ti, trc.Query.Masks); err != nil {
+		return err
+	}
 	trv.query.disable.funcs = trc.Query.DisableFunctions
+	trv.query.deleted = trc.Query.WithDeleted
 	trv.query.block = trc.Query.Block
 
 	// insert config
 	trv.insert.cols = makeSet(trc.Insert.Columns)
+	if trv.insert.check, err = compileCheck(ti, trc.Insert.Check); err != nil {
+		return err
+	}
+	if err := checkPresets(ti, trc.Insert.Presets); err != nil {
+		return err
+	}
 	trv.insert.presets = trc.Insert.Presets
 	trv.insert.block = trc.Insert.Block
 
@@ -133,6 +155,12 @@ func (co *Compiler) AddRole(role, table string, trc TRConfig) error {
 		return err
 	}
 	trv.update.cols = makeSet(trc.Update.Columns)
+	if trv.update.check, err = compileCheck(ti, trc.Update.Check); err != nil {
+		return err
+	}
+	if err := checkPresets(ti, trc.Update.Presets); err != nil {
+		return err
+	}
 	trv.update.presets = trc.Update.Presets
 	trv.update.block = trc.Update.Block
 
@@ -142,6 +170,12 @@ func (co *Compiler) AddRole(role, table string, trc TRConfig) error {
 		return err
 	}
 	trv.upsert.cols = makeSet(trc.Upsert.Columns)
+	if trv.upsert.check, err = compileCheck(ti, trc.Upsert.Check); err != nil {
+		return err
+	}
+	if err := checkPresets(ti, trc.Upsert.Presets); err != nil {
+		return err
+	}
 	trv.upsert.presets = trc.Upsert.Presets
 	trv.upsert.block = trc.Upsert.Block
 
@@ -156,16 +190,56 @@ func (co *Compiler) AddRole(role, table string, trc TRConfig) error {
 	singular := flect.Singularize(table)
 	plural := flect.Pluralize(table)
 
+	co.rw.Lock()
 	co.tr[(role + singular)] = trv
 	co.tr[(role + plural)] = trv
+	co.rw.Unlock()
 
 	return nil
 }
 
+// IsBlocked returns true if the role is not allowed to run
+// the operation on the table
+func (co *Compiler) IsBlocked(role, table string, qt QType) bool {
+	tr := co.getRole(role, table)
+	return tr.isBlocked(qt, table) != nil
+}
+
+// IsColumnAllowed returns true if the role can use the column
+// of the table with the operation
+func (co *Compiler) IsColumnAllowed(role, table, col string, qt QType) bool {
+	tr := co.getRole(role, table)
+	return tr.colAllowed(qt, col)
+}
+
+// IsColumnMasked returns true if the column of the table is masked
+// for the role
+func (co *Compiler) IsColumnMasked(role, table, col string) bool {
+	tr := co.getRole(role, table)
+	return tr.mask(col) != MaskTypeNone
+}
+
+// HasMaskedColumns returns true if any column of the table is
+// masked for the role
+func (co *Compiler) HasMaskedColumns(role, table string) bool {
+	tr := co.getRole(role, table)
+	return len(tr.query.masks) != 0
+}
+
+// IsFuncsBlocked returns true if the role is not allowed to use
+// functions and aggregates on the table
+func (co *Compiler) IsFuncsBlocked(role, table string) bool {
+	tr := co.getRole(role, table)
+	return tr.isFuncsBlocked()
+}
+
 func (co *Compiler) getRole(role, field string) trval {
 	var tr trval
 	var ok bool
 
+	co.rw.RLock()
+	defer co.rw.RUnlock()
+
 	// For anon roles when a trval is not found return the default trval
 	if tr, ok = co.tr[(role + field)]; !ok && role != "anon" {
 		tr.role = role
@@ -193,8 +267,30 @@ func (trv *trval) filter(qt QType) (*Exp, bool) {
 	return nil, false
 }
 
-func (trv *trval) columnAllowed(qt *QCode, name string) bool {
-	switch qt.SType {
+// check returns the expression the rows written by a mutation
+// must satisfy, nil if no check is defined
+func (trv *trval) check(mt MType) *Exp {
+	switch mt {
+	case MTInsert:
+		return trv.insert.check
+	case MTUpdate:
+		return trv.update.check
+	case MTUpsert:
+		return trv.upsert.check
+	}
+	return nil
+}
+
+func (trv *trval) mask(name string) MaskType {
+	return trv.query.masks[name]
+}
+
+func (trv *trval) columnAllowed(qc *QCode, name string) bool {
+	return trv.colAllowed(qc.SType, name)
+}
+
+func (trv *trval) colAllowed(qt QType, name string) bool {
+	switch qt {
 	case QTQuery:
 		_, ok := trv.query.cols[name]
 		return ok || len(trv.query.cols) == 0
@@ -250,6 +346,10 @@ func (trv *trval) isFuncsBlocked() bool {
 	return trv.query.disable.funcs
 }
 
+func (trv *trval) withDeleted() bool {
+	return trv.query.deleted
+}
+
 // func (trv *trval) isMutationBlocked(mt MType, name string) error {
 // 	var blocked bool
 // 	switch mt {
@@ -280,6 +380,64 @@ func (trv *trval) getPresets(mt MType) map[string]string {
 	return nil
 }
 
+func compileCheck(ti sdata.DBTableInfo, check []string) (*Exp, error) {
+	if len(check) == 0 {
+		return nil, nil
+	}
+
+	ex, _, err := compileFilter(ti, check)
+	if err != nil {
+		return nil, fmt.Errorf("check: %w", err)
+	}
+	return ex, nil
+}
+
+func compileMasks(ti sdata.DBTableInfo, masks map[string]string) (map[string]MaskType, error) {
+	if len(masks) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]MaskType, len(masks))
+
+	for k, v := range masks {
+		var mt MaskType
+
+		switch strings.ToLower(v) {
+		case "null":
+			mt = MaskTypeNull
+		case "email":
+			mt = MaskTypeEmail
+		case "last4":
+			mt = MaskTypeLast4
+		case "hash":
+			mt = MaskTypeHash
+		default:
+			return nil, fmt.Errorf("masks: %s: unknown mask type: %s", k, v)
+		}
+
+		col, err := ti.GetColumn(k)
+		if err != nil {
+			return nil, fmt.Errorf("masks: %w", err)
+		}
+		if col.Encrypted {
+			return nil, fmt.Errorf("masks: column is encrypted: %s", col.Name)
+		}
+		m[col.Name] = mt
+	}
+	return m, nil
+}
+
+// checkPresets ensures presets are not set on encrypted columns since
+// their values are rendered into the query and cannot be encrypted
+func checkPresets(ti sdata.DBTableInfo, presets map[string]string) error {
+	for k := range presets {
+		if col, err := ti.GetColumn(k); err == nil && col.Encrypted {
+			return fmt.Errorf("presets: column is encrypted: %s", col.Name)
+		}
+	}
+	return nil
+}
+
 func makeSet(list []string) map[string]struct{} {
 	m := make(map[string]struct{}, len(list))
 
@@ -0,0 +1,113 @@
+package qcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
+)
+
+const connSuffix = "_connection"
+
+// connFields are the fields that can be selected on a connection
+// and on its edges and page info
+var connFields = map[string][]string{
+	"":         {"edges", "pageInfo", "totalCount"},
+	"edges":    {"cursor", "node"},
+	"pageInfo": {"hasNextPage", "hasPreviousPage", "startCursor", "endCursor"},
+}
+
+func (co *Compiler) isConnection(field *graph.Field) bool {
+	if !strings.HasSuffix(field.Name, connSuffix) || len(field.Children) == 0 {
+		return false
+	}
+	// tables named with the suffix are not connections
+	_, err := co.s.GetTableInfo(field.Name, "")
+	return err != nil
+}
+
+// compileConnection compiles the fields selected on the connection and
+// returns the node field which has the columns and child tables to select
+func (co *Compiler) compileConnection(
+	qc *QCode, op *graph.Operation, field *graph.Field, sel *Select) (*graph.Field, error) {
+	var err error
+
+	if qc.Type == QTMutation {
+		return nil, fmt.Errorf("connections are not supported with mutations: %s", sel.FieldName)
+	}
+	if co.s.Type() == "mysql" {
+		return nil, fmt.Errorf("connections are not supported with mysql: %s", sel.FieldName)
+	}
+	if sel.Singular {
+		return nil, fmt.Errorf("connections must use the plural table name: %s", sel.FieldName)
+	}
+
+	node := &graph.Field{}
+
+	if sel.Conn, err = compileConnFields(op, field, "", &node); err != nil {
+		return nil, err
+	}
+
+	// every row needs a cursor
+	sel.Paging.Cursor = true
+
+	return node, nil
+}
+
+func compileConnFields(
+	op *graph.Operation, field *graph.Field, parent string, node **graph.Field) ([]ConnField, error) {
+	var err error
+
+	fields := make([]ConnField, 0, len(field.Children))
+
+	for _, cid := range field.Children {
+		f := &op.Fields[cid]
+
+		// field names are lowercased by the parser
+		name, ok := connFieldName(parent, f.Name)
+		if !ok {
+			return nil, fmt.Errorf("connection: unknown field '%s'", f.Name)
+		}
+
+		cf := ConnField{Name: name, FieldName: name}
+		if f.Alias != "" {
+			cf.FieldName = f.Alias
+		}
+
+		_, isObj := connFields[name]
+		isObj = isObj || name == "node"
+
+		if isObj != (len(f.Children) != 0) {
+			return nil, fmt.Errorf("connection: invalid selection for field '%s'", f.Name)
+		}
+
+		switch {
+		case name == "node":
+			if len((*node).Children) != 0 {
+				return nil, fmt.Errorf("connection: field 'node' selected more than once")
+			}
+			*node = f
+
+		case isObj:
+			if cf.Fields, err = compileConnFields(op, f, name, node); err != nil {
+				return nil, err
+			}
+		}
+
+		fields = append(fields, cf)
+	}
+
+	return fields, nil
+}
+
+func connFieldName(parent, name string) (string, bool) {
+	if name == "__typename" {
+		return name, true
+	}
+	for _, v := range connFields[parent] {
+		if strings.EqualFold(v, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -9,12 +9,12 @@ import (
 	"github.com/dosco/graphjin/core/internal/util"
 )
 
-func (co *Compiler) compileArgObj(ti sdata.DBTableInfo, st *util.StackInf, arg *graph.Arg) (*Exp, bool, error) {
+func (co *Compiler) compileArgObj(ti sdata.DBTableInfo, tr *trval, st *util.StackInf, arg *graph.Arg) (*Exp, bool, error) {
 	if arg.Val.Type != graph.NodeObj {
 		return nil, false, fmt.Errorf("expecting an object")
 	}
 
-	return co.compileArgNode(ti, st, arg.Val, true)
+	return co.compileArgNode(ti, nil, tr, st, arg.Val, true)
 }
 
 type aexp struct {
@@ -22,8 +22,13 @@ type aexp struct {
 	node *graph.Node
 }
 
+// compileArgNode compiles a filter expression, when hsel is set it's
+// the having clause of the select and the fields are aggregate functions.
+// The columns are checked against the role config when tr is set.
 func (co *Compiler) compileArgNode(
 	ti sdata.DBTableInfo,
+	hsel *Select,
+	tr *trval,
 	st *util.StackInf,
 	node *graph.Node,
 	usePool bool) (*Exp, bool, error) {
@@ -55,7 +60,7 @@ func (co *Compiler) compileArgNode(
 			continue
 		}
 
-		ex, err := newExp(ti, st, av, usePool)
+		ex, err := co.newExp(ti, hsel, tr, st, av, usePool)
 		if err != nil {
 			return nil, needsUser, err
 		}
@@ -78,7 +83,13 @@ func (co *Compiler) compileArgNode(
 	return root, needsUser, nil
 }
 
-func newExp(ti sdata.DBTableInfo, st *util.StackInf, av aexp, usePool bool) (*Exp, error) {
+func (co *Compiler) newExp(
+	ti sdata.DBTableInfo,
+	hsel *Select,
+	tr *trval,
+	st *util.StackInf,
+	av aexp,
+	usePool bool) (*Exp, error) {
 	node := av.node
 	name := node.Name
 
@@ -218,14 +229,77 @@ func newExp(ti sdata.DBTableInfo, st *util.StackInf, av aexp, usePool bool) (*Ex
 			return nil, fmt.Errorf("[Where] invalid values for: %s", name)
 		}
 
+		if hsel != nil {
+			return ex, co.setHavingFunc(hsel, tr, ex, node)
+		}
+
 		if err := setWhereColName(ti, ex, node); err != nil {
 			return nil, err
 		}
+
+		if err := checkEncryptedCol(ex); err != nil {
+			return nil, err
+		}
+
+		if err := co.checkMaskedCol(tr, ex); err != nil {
+			return nil, err
+		}
 	}
 
 	return ex, nil
 }
 
+// checkMaskedCol ensures masked columns are not filtered on since
+// the rows returned would reveal the unmasked value
+func (co *Compiler) checkMaskedCol(tr *trval, ex *Exp) error {
+	if tr == nil {
+		return nil
+	}
+
+	mtr := *tr
+	if len(ex.Rels) != 0 {
+		mtr = co.getRole(tr.role, ex.Rels[len(ex.Rels)-1].Left.Ti.Name)
+	}
+
+	if mtr.mask(ex.Col.Name) != MaskTypeNone {
+		return fmt.Errorf("[Where] column masked: %s (%s)", ex.Col.Name, tr.role)
+	}
+	return nil
+}
+
+// checkEncryptedCol ensures encrypted columns are only filtered on when
+// they use deterministic encryption and then only using equality operators
+// with a variable so the value can be encrypted before it's sent to the database.
+func checkEncryptedCol(ex *Exp) error {
+	if !ex.Col.Encrypted || ex.Op == OpIsNull {
+		return nil
+	}
+
+	if !ex.Col.Deterministic {
+		return fmt.Errorf("[Where] column is encrypted: %s", ex.Col.Name)
+	}
+
+	switch ex.Op {
+	case OpEquals, OpNotEquals, OpIn, OpNotIn, OpNotDistinct, OpDistinct:
+	default:
+		return fmt.Errorf("[Where] only equality operators are supported on encrypted column: %s", ex.Col.Name)
+	}
+
+	if ex.Type != ValVar {
+		return fmt.Errorf("[Where] a variable is required for encrypted column: %s", ex.Col.Name)
+	}
+
+	// the value is encrypted with each of the secret keys so rows saved
+	// before the key was rotated still match
+	switch ex.Op {
+	case OpEquals, OpNotDistinct:
+		ex.Op = OpIn
+	case OpNotEquals, OpDistinct:
+		ex.Op = OpNotIn
+	}
+	return nil
+}
+
 func setListVal(ex *Exp, node *graph.Node) {
 	if len(node.Children) != 0 {
 		switch node.Children[0].Type {
@@ -2,7 +2,10 @@ package qcode
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
 )
 
 func (co *Compiler) isFunction(sel *Select, fname string) (Function, bool, error) {
@@ -44,11 +47,33 @@ func (co *Compiler) isFunction(sel *Select, fname string) (Function, bool, error
 		fn.skip = true
 
 	default:
+		if name, col, ok := windowFunc(sel.Ti, fname); ok {
+			fn.Name = name
+			fn.Window = &Window{}
+			cn = col
+			break
+		}
+
 		n := co.funcPrefixLen(fname)
 		if n != 0 {
 			cn = fname[n:]
 			fn.Name = fname[:(n - 1)]
 			agg = true
+
+			if co.s.Type() == "mysql" {
+				switch fn.Name {
+				case "percentile_cont", "percentile_disc", "mode":
+					return fn, false, fmt.Errorf("%s is not supported with mysql: %s", fn.Name, fname)
+				}
+			}
+
+		} else if col, unit, ok := isTimeBucket(sel.Ti, fname); ok {
+			if co.s.Type() == "mysql" {
+				return fn, false, fmt.Errorf("time buckets are not supported with mysql: %s", fname)
+			}
+			fn.Name = "date_trunc"
+			fn.Col = col
+			fn.Arg = unit
 		}
 	}
 
@@ -60,9 +85,13 @@ func (co *Compiler) isFunction(sel *Select, fname string) (Function, bool, error
 }
 
 func (co *Compiler) funcPrefixLen(col string) int {
+	// longer prefixes are matched first, for example
+	// count_distinct_ before count_
 	switch {
 	case strings.HasPrefix(col, "avg_"):
 		return 4
+	case strings.HasPrefix(col, "count_distinct_"):
+		return 15
 	case strings.HasPrefix(col, "count_"):
 		return 6
 	case strings.HasPrefix(col, "max_"):
@@ -71,18 +100,32 @@ func (co *Compiler) funcPrefixLen(col string) int {
 		return 4
 	case strings.HasPrefix(col, "sum_"):
 		return 4
-	case strings.HasPrefix(col, "stddev_"):
-		return 7
 	case strings.HasPrefix(col, "stddev_pop_"):
 		return 11
 	case strings.HasPrefix(col, "stddev_samp_"):
 		return 12
+	case strings.HasPrefix(col, "stddev_"):
+		return 7
 	case strings.HasPrefix(col, "variance_"):
 		return 9
 	case strings.HasPrefix(col, "var_pop_"):
 		return 8
 	case strings.HasPrefix(col, "var_samp_"):
 		return 9
+	case strings.HasPrefix(col, "percentile_cont_"):
+		return 16
+	case strings.HasPrefix(col, "percentile_disc_"):
+		return 16
+	case strings.HasPrefix(col, "array_agg_"):
+		return 10
+	case strings.HasPrefix(col, "string_agg_"):
+		return 11
+	case strings.HasPrefix(col, "bool_and_"):
+		return 9
+	case strings.HasPrefix(col, "bool_or_"):
+		return 8
+	case strings.HasPrefix(col, "mode_"):
+		return 5
 	}
 	fnLen := len(col)
 
@@ -95,3 +138,49 @@ func (co *Compiler) funcPrefixLen(col string) int {
 
 	return 0
 }
+
+// compileFuncArgs sets the argument of the aggregate functions that take one,
+// the fraction for percentile_cont and percentile_disc and the separator for string_agg
+func compileFuncArgs(fn *Function, args []graph.Arg) error {
+	var name string
+
+	switch fn.Name {
+	case "percentile_cont", "percentile_disc":
+		name = "fraction"
+	case "string_agg":
+		name = "separator"
+		fn.Arg = ","
+	default:
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg.Name != name {
+			return fmt.Errorf("%s: unknown argument '%s'", fn.Name, arg.Name)
+		}
+
+		switch name {
+		case "fraction":
+			if arg.Val.Type != graph.NodeNum {
+				return argErr(name, "number")
+			}
+			if v, err := strconv.ParseFloat(arg.Val.Val, 64); err != nil || v < 0 || v > 1 {
+				return fmt.Errorf("%s: fraction must be between 0 and 1", fn.Name)
+			}
+
+		case "separator":
+			if arg.Val.Type != graph.NodeStr {
+				return argErr(name, "string")
+			}
+			if strings.ContainsAny(arg.Val.Val, `'\`) {
+				return fmt.Errorf("%s: separator cannot contain quotes or backslashes", fn.Name)
+			}
+		}
+		fn.Arg = arg.Val.Val
+	}
+
+	if name == "fraction" && fn.Arg == "" {
+		return fmt.Errorf("%s: argument '%s' required", fn.Name, name)
+	}
+	return nil
+}
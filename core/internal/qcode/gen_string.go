@@ -60,11 +60,12 @@ func _() {
 	_ = x[SelTypeNone-0]
 	_ = x[SelTypeUnion-1]
 	_ = x[SelTypeMember-2]
+	_ = x[SelTypeNode-3]
 }
 
-const _SelType_name = "SelTypeNoneSelTypeUnionSelTypeMember"
+const _SelType_name = "SelTypeNoneSelTypeUnionSelTypeMemberSelTypeNode"
 
-var _SelType_index = [...]uint8{0, 11, 23, 36}
+var _SelType_index = [...]uint8{0, 11, 23, 36, 47}
 
 func (i SelType) String() string {
 	if i < 0 || i >= SelType(len(_SelType_index)-1) {
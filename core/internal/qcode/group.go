@@ -0,0 +1,128 @@
+package qcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+// timeBuckets are the units a timestamp can be truncated to
+// using fields like created_at_by_day
+var timeBuckets = []string{"minute", "hour", "day", "week", "month", "quarter", "year"}
+
+// isTimeBucket returns the column and unit of a time bucket field
+func isTimeBucket(ti sdata.DBTableInfo, fname string) (sdata.DBColumn, string, bool) {
+	var col sdata.DBColumn
+
+	i := strings.LastIndex(fname, "_by_")
+	if i < 1 {
+		return col, "", false
+	}
+
+	// columns named with the suffix are not time buckets
+	if _, err := ti.GetColumn(fname); err == nil {
+		return col, "", false
+	}
+
+	unit := fname[(i + 4):]
+	for _, v := range timeBuckets {
+		if v != unit {
+			continue
+		}
+		col, err := ti.GetColumn(fname[:i])
+		if err != nil || !isTimeCol(col) {
+			return col, "", false
+		}
+		return col, unit, true
+	}
+	return col, "", false
+}
+
+func isTimeCol(col sdata.DBColumn) bool {
+	t := strings.ToLower(col.Type)
+	return t == "date" || strings.HasPrefix(t, "timestamp")
+}
+
+// setHavingFunc sets the aggregate function and column of an
+// expression in the having clause for example sum_price
+func (co *Compiler) setHavingFunc(sel *Select, tr *trval, ex *Exp, node *graph.Node) error {
+	var name string
+
+	for n := node.Parent; n != nil; n = n.Parent {
+		if n.Type != graph.NodeObj || n.Name == "" {
+			continue
+		}
+		switch n.Name {
+		case "and", "or", "not", "_and", "_or", "_not":
+			continue
+		}
+		if name != "" {
+			return fmt.Errorf("[Having] nested fields are not supported: %s", n.Name)
+		}
+		name = n.Name
+	}
+
+	switch name {
+	case "":
+		return fmt.Errorf("invalid having clause")
+	}
+
+	if tr != nil && tr.isFuncsBlocked() {
+		return fmt.Errorf("[Having] functions blocked: %s (%s)", name, tr.role)
+	}
+
+	switch name {
+	case "count":
+		ex.Func = name
+		ex.Col = sdata.DBColumn{Type: "bigint"}
+		return nil
+	}
+
+	fn, agg, err := co.isFunction(sel, name)
+	if err != nil {
+		return err
+	}
+	if !agg {
+		return fmt.Errorf("[Having] '%s' is not an aggregate function", name)
+	}
+	if fn.Col.Encrypted {
+		return fmt.Errorf("[Having] column is encrypted: %s", fn.Col.Name)
+	}
+
+	// the same as the columns selected, filtering on an aggregate of a
+	// blocked or masked column would leak its values
+	if tr != nil {
+		if !tr.colAllowed(QTQuery, fn.Col.Name) {
+			return fmt.Errorf("[Having] column blocked: %s (%s)", fn.Col.Name, tr.role)
+		}
+		if tr.mask(fn.Col.Name) != MaskTypeNone {
+			return fmt.Errorf("[Having] column masked: %s (%s)", fn.Col.Name, tr.role)
+		}
+	}
+
+	switch fn.Name {
+	case "percentile_cont", "percentile_disc", "array_agg", "string_agg":
+		return fmt.Errorf("[Having] '%s' is not supported", name)
+	}
+
+	ex.Func = fn.Name
+	ex.Col = fn.Col
+	ex.Col.Type = aggColType(fn.Name, fn.Col.Type)
+	return nil
+}
+
+// aggColType returns the type of the value returned by the aggregate function
+func aggColType(fn, colType string) string {
+	switch fn {
+	case "count", "count_distinct":
+		return "bigint"
+	case "bool_and", "bool_or":
+		return "boolean"
+	case "avg", "stddev", "stddev_pop", "stddev_samp", "variance", "var_pop", "var_samp":
+		return "numeric"
+	default:
+		return colType
+	}
+}
@@ -60,6 +60,7 @@ type Mutate struct {
 	Items  []Mutate
 	Multi  bool
 	MID    int32
+	Check  *Exp
 	render bool
 }
 
@@ -236,6 +237,9 @@ func (co *Compiler) newMutate(st *util.StackInf, m Mutate, role string) error {
 		return err
 	}
 
+	// Rows written must satisfy the role's check expression
+	m.Check = tr.check(m.Type)
+
 	// For inserts order the children according to
 	// the creation order required by the parent-to-child
 	// relationships. For example users need to be created
@@ -372,6 +376,15 @@ func addTablesAndColumns(m Mutate, tr trval) (Mutate, error) {
 		}
 
 	case MTUpdate:
+		// The version column is compared with the value in the input and
+		// bumped by the update so it's never set from the input
+		if vc := m.Ti.VersionCol; vc.Name != "" {
+			if _, ok := m.Data[vc.Name]; !ok {
+				return m, fmt.Errorf("required: '%s' needed to update '%s'", vc.Name, m.Ti.Name)
+			}
+			cm[vc.Name] = struct{}{}
+		}
+
 		// Render tables needed to set values if child-to-parent
 		// relationship is one-to-many
 		for _, v := range m.Items {
@@ -0,0 +1,125 @@
+package qcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
+	"github.com/dosco/graphjin/core/internal/util"
+)
+
+const globalIDField = "id"
+
+// isGlobalID returns true if the field is the global id of the table,
+// it's made up of the table name and the primary key
+func (co *Compiler) isGlobalID(sel *Select, name string) bool {
+	return co.c.GlobalIDs && name == globalIDField && sel.Ti.PrimaryCol.Name != ""
+}
+
+// isNode returns true for the root fields used to fetch rows
+// by global id, tables with the same name take precedence
+func (co *Compiler) isNode(field *graph.Field) bool {
+	if !co.c.GlobalIDs || field.ParentID != -1 {
+		return false
+	}
+	if field.Name != "node" && field.Name != "nodes" {
+		return false
+	}
+	_, err := co.s.GetTableInfo(field.Name, "")
+	return err != nil
+}
+
+// compileNode compiles the node and nodes fields. The fields of each
+// table are selected using an inline fragment (eg. ... on product),
+// fields outside the fragments are added to each of them.
+func (co *Compiler) compileNode(
+	qc *QCode, op *graph.Operation, st *util.StackInt32, field *graph.Field, sel *Select) error {
+
+	if qc.Type == QTMutation {
+		return fmt.Errorf("%s: not supported with mutations", field.Name)
+	}
+	if co.s.Type() == "mysql" {
+		return fmt.Errorf("%s: not supported with mysql", field.Name)
+	}
+
+	sel.Type = SelTypeNode
+	sel.Singular = (field.Name == "node")
+
+	argName := "id"
+	if !sel.Singular {
+		argName = "ids"
+	}
+
+	for i := range field.Args {
+		arg := &field.Args[i]
+
+		if arg.Name != argName {
+			return fmt.Errorf("%s: unknown argument '%s'", field.Name, arg.Name)
+		}
+		if arg.Val.Type != graph.NodeVar {
+			return argErr(argName, "variable")
+		}
+		sel.addArg(arg)
+	}
+
+	if _, ok := sel.ArgMap[argName]; !ok {
+		return fmt.Errorf("%s: argument '%s' is required", field.Name, argName)
+	}
+
+	var common, members []int32
+
+	// the parser marks all the fields of a union as members,
+	// only the inline fragments have fields
+	for _, cid := range field.Children {
+		f := &op.Fields[cid]
+		if f.Type == graph.FieldMember && len(f.Children) != 0 {
+			members = append(members, cid)
+		} else {
+			common = append(common, cid)
+		}
+	}
+
+	if len(members) == 0 {
+		return fmt.Errorf("%s: use inline fragments to select the fields of each table (eg. ... on product)",
+			field.Name)
+	}
+
+	qc.Roots = append(qc.Roots, sel.ID)
+
+	for _, cid := range members {
+		f := &op.Fields[cid]
+
+		// the arguments of the node are copied to the fragments by the parser
+		f.Args = nil
+		f.Children = append(common[:len(common):len(common)], f.Children...)
+
+		st.Push(f.ID | (sel.ID << 16))
+	}
+
+	return nil
+}
+
+// addNodeMember sets the table of an inline fragment of a node, it
+// can be named by the table (eg. product) or its type (eg. productOutput)
+func (co *Compiler) addNodeMember(field *graph.Field, sel *Select) error {
+	var err error
+
+	sel.Type = SelTypeMember
+	sel.Singular = true
+
+	name := field.Name
+	if sel.Ti, err = co.s.GetTableInfo(name, ""); err != nil {
+		name = strings.TrimSuffix(name, "output")
+		if sel.Ti, err = co.s.GetTableInfo(name, ""); err != nil {
+			return fmt.Errorf("node: unknown table '%s'", field.Name)
+		}
+	}
+
+	if sel.Ti.PrimaryCol.Name == "" {
+		return fmt.Errorf("node: no primary key column defined for %s", sel.Ti.Name)
+	}
+
+	sel.Table = sel.Ti.Name
+	field.Name = name
+	return nil
+}
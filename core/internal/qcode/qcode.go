@@ -37,6 +37,7 @@ const (
 	SelTypeNone SelType = iota
 	SelTypeUnion
 	SelTypeMember
+	SelTypeNode
 )
 
 type SkipType int8
@@ -47,6 +48,16 @@ const (
 	SkipTypeRemote
 )
 
+type MaskType int8
+
+const (
+	MaskTypeNone MaskType = iota
+	MaskTypeNull
+	MaskTypeEmail
+	MaskTypeLast4
+	MaskTypeHash
+)
+
 type QCode struct {
 	Type      QType
 	SType     QType
@@ -77,26 +88,34 @@ type Select struct {
 	Where      Filter
 	OrderBy    []OrderBy
 	GroupCols  bool
+	Having     Filter
 	DistinctOn []sdata.DBColumn
 	Paging     Paging
+	Conn       []ConnField
+	Aggregate  bool
 	Children   []int32
 	SkipRender SkipType
 	Ti         sdata.DBTableInfo
 	Rel        sdata.DBRel
 	order      Order
 	through    string
+	deleted    bool
 }
 
 type Column struct {
 	Col       sdata.DBColumn
 	FieldName string
 	Base      bool
+	Mask      MaskType
+	GlobalID  bool
 }
 
 type Function struct {
 	Name      string
 	Col       sdata.DBColumn
 	FieldName string
+	Arg       string
+	Window    *Window
 	skip      bool
 }
 
@@ -113,6 +132,7 @@ type Exp struct {
 	Val       string
 	ListType  ValType
 	ListVal   []string
+	Func      string
 	Children  []*Exp
 	childrenA [5]*Exp
 	internal  bool
@@ -156,6 +176,17 @@ type Paging struct {
 	Offset    int32
 	Cursor    bool
 	NoLimit   bool
+
+	// Connection is set for Relay style connections, the rows are
+	// returned as edges along with the page info and total count
+	Connection bool
+}
+
+// ConnField is a field selected on a connection, edges or page info
+type ConnField struct {
+	Name      string
+	FieldName string
+	Fields    []ConnField
 }
 
 type ExpOp int8
@@ -234,6 +265,7 @@ type Compiler struct {
 	c  Config
 	s  *sdata.DBSchema
 	tr map[string]trval
+	rw sync.RWMutex
 }
 
 var expPool = sync.Pool{
@@ -369,6 +401,31 @@ func (co *Compiler) compileQuery(qc *QCode, op *graph.Operation, role string) er
 
 		sel.Children = make([]int32, 0, 5)
 
+		// The node and nodes fields fetch rows of any table
+		// using their global ids
+		if co.isNode(field) {
+			if err := co.compileNode(qc, op, st, field, sel); err != nil {
+				return err
+			}
+			qc.Selects = append(qc.Selects, s1)
+			id++
+			continue
+		}
+
+		// A connection is queried using the plural table name
+		// with a suffix. For example products_connection
+		if co.isConnection(field) {
+			field.Name = strings.TrimSuffix(field.Name, connSuffix)
+			sel.Paging.Connection = true
+		}
+
+		// Aggregates over a table or relationship are queried using
+		// the plural table name with a suffix. For example products_aggregate
+		if co.isAggregate(field) {
+			field.Name = strings.TrimSuffix(field.Name, aggSuffix)
+			sel.Aggregate = true
+		}
+
 		if err := co.compileDirectives(qc, sel, field.Directives); err != nil {
 			return err
 		}
@@ -395,11 +452,26 @@ func (co *Compiler) compileQuery(qc *QCode, op *graph.Operation, role string) er
 
 		co.setLimit(tr, qc, sel)
 
-		if err := co.compileArgs(qc, sel, field.Args, role); err != nil {
+		if err := co.compileArgs(qc, sel, field.Args, tr); err != nil {
 			return err
 		}
 
-		if err := co.compileColumns(field, op, st, qc, sel, tr); err != nil {
+		if sel.Aggregate {
+			if err := co.compileAggregate(qc, field, sel, tr); err != nil {
+				return err
+			}
+		}
+
+		cf := field
+
+		if sel.Paging.Connection {
+			var err error
+			if cf, err = co.compileConnection(qc, op, field, sel); err != nil {
+				return err
+			}
+		}
+
+		if err := co.compileColumns(cf, op, st, qc, sel, tr); err != nil {
 			return err
 		}
 
@@ -408,6 +480,8 @@ func (co *Compiler) compileQuery(qc *QCode, op *graph.Operation, role string) er
 			sel.SkipRender = SkipTypeUserNeeded
 		}
 
+		addSoftDeleteFilter(qc, sel, tr)
+
 		// If an actual cursor is avalable
 		if sel.Paging.Cursor {
 			// Set tie-breaker order column for the cursor direction
@@ -462,6 +536,10 @@ func (co *Compiler) addRelInfo(field *graph.Field, qc *QCode, sel *Select) error
 		}
 
 	case graph.FieldMember:
+		if psel.Type == SelTypeNode {
+			return co.addNodeMember(field, sel)
+		}
+
 		// TODO: Fix this
 		// if sel.Table != sel.Table {
 		// 	return fmt.Errorf("inline fragment: 'on %s' should be 'on %s'", sel.Table, sel.Table)
@@ -614,6 +692,35 @@ func addFilters(qc *QCode, sel *Select, tr trval) bool {
 	return false
 }
 
+// addSoftDeleteFilter excludes rows marked as deleted unless the role or
+// query asks for them. It's not added to the root of a delete mutation
+// since the deleted rows are returned by it.
+func addSoftDeleteFilter(qc *QCode, sel *Select, tr trval) {
+	col := sel.Ti.DeletedCol
+
+	if col.Name == "" || sel.deleted || tr.withDeleted() {
+		return
+	}
+
+	if qc.SType == QTDelete && sel.ParentID == -1 {
+		return
+	}
+
+	ex := NewFilter()
+	ex.Col = col
+
+	if col.IsBool() {
+		ex.Op = OpDistinct
+		ex.Type = ValBool
+		ex.Val = "true"
+	} else {
+		ex.Op = OpIsNull
+		ex.Val = "true"
+	}
+
+	setFilter(sel, ex)
+}
+
 func (co *Compiler) compileDirectives(qc *QCode, sel *Select, dirs []graph.Directive) error {
 	var err error
 
@@ -642,7 +749,7 @@ func (co *Compiler) compileDirectives(qc *QCode, sel *Select, dirs []graph.Direc
 	return nil
 }
 
-func (co *Compiler) compileArgs(qc *QCode, sel *Select, args []graph.Arg, role string) error {
+func (co *Compiler) compileArgs(qc *QCode, sel *Select, args []graph.Arg, tr trval) error {
 	var err error
 
 	for i := range args {
@@ -653,16 +760,19 @@ func (co *Compiler) compileArgs(qc *QCode, sel *Select, args []graph.Arg, role s
 			err = co.compileArgID(sel, arg)
 
 		case "search":
-			err = co.compileArgSearch(sel, arg)
+			err = co.compileArgSearch(sel, arg, tr)
 
 		case "where":
-			err = co.compileArgWhere(sel.Ti, sel, arg, role)
+			err = co.compileArgWhere(sel.Ti, sel, arg, tr)
+
+		case "having":
+			err = co.compileArgHaving(sel, arg, tr)
 
 		case "orderby", "order_by", "order":
-			err = co.compileArgOrderBy(sel, arg)
+			err = co.compileArgOrderBy(sel, arg, tr)
 
 		case "distinct_on", "distinct":
-			err = co.compileArgDistinctOn(sel, arg)
+			err = co.compileArgDistinctOn(sel, arg, tr)
 
 		case "limit":
 			err = co.compileArgLimit(sel, arg)
@@ -684,6 +794,9 @@ func (co *Compiler) compileArgs(qc *QCode, sel *Select, args []graph.Arg, role s
 
 		case "find":
 			err = co.compileArgFind(sel, arg)
+
+		case "with_deleted":
+			err = co.compileArgWithDeleted(sel, arg)
 		}
 
 		if err != nil {
@@ -818,6 +931,17 @@ func (co *Compiler) compileArgFind(sel *Select, arg *graph.Arg) error {
 	return nil
 }
 
+func (co *Compiler) compileArgWithDeleted(sel *Select, arg *graph.Arg) error {
+	if sel.Ti.DeletedCol.Name == "" {
+		return fmt.Errorf("with_deleted: table '%s' has no soft delete column", sel.Table)
+	}
+	if arg.Val.Type != graph.NodeBool {
+		return argErr("with_deleted", "boolean")
+	}
+	sel.deleted = (arg.Val.Val == "true")
+	return nil
+}
+
 func (co *Compiler) compileArgID(sel *Select, arg *graph.Arg) error {
 	if sel.ParentID != -1 {
 		return fmt.Errorf("argument 'id' can only be specified at the query root")
@@ -843,7 +967,7 @@ func (co *Compiler) compileArgID(sel *Select, arg *graph.Arg) error {
 	return nil
 }
 
-func (co *Compiler) compileArgSearch(sel *Select, arg *graph.Arg) error {
+func (co *Compiler) compileArgSearch(sel *Select, arg *graph.Arg, tr trval) error {
 	if co.s.Type() == "mysql" {
 		return fmt.Errorf("mysql: search not supported")
 	}
@@ -851,6 +975,12 @@ func (co *Compiler) compileArgSearch(sel *Select, arg *graph.Arg) error {
 		return fmt.Errorf("no tsv column defined for %s", sel.Table)
 	}
 
+	// the search index can include the masked columns
+	if len(tr.query.masks) != 0 {
+		return fmt.Errorf("search not allowed on table with masked columns: %s (%s)",
+			sel.Table, tr.role)
+	}
+
 	if arg.Val.Type != graph.NodeVar {
 		return argErr("search", "variable")
 	}
@@ -868,23 +998,39 @@ func (co *Compiler) compileArgSearch(sel *Select, arg *graph.Arg) error {
 	return nil
 }
 
-func (co *Compiler) compileArgWhere(ti sdata.DBTableInfo, sel *Select, arg *graph.Arg, role string) error {
+func (co *Compiler) compileArgWhere(ti sdata.DBTableInfo, sel *Select, arg *graph.Arg, tr trval) error {
 	st := util.NewStackInf()
 	var err error
 
-	ex, nu, err := co.compileArgObj(ti, st, arg)
+	ex, nu, err := co.compileArgObj(ti, &tr, st, arg)
 	if err != nil {
 		return err
 	}
 
-	if nu && role == "anon" {
+	if nu && tr.role == "anon" {
 		sel.SkipRender = SkipTypeUserNeeded
 	}
 	setFilter(sel, ex)
 	return nil
 }
 
-func (co *Compiler) compileArgOrderBy(sel *Select, arg *graph.Arg) error {
+func (co *Compiler) compileArgHaving(sel *Select, arg *graph.Arg, tr trval) error {
+	if arg.Val.Type != graph.NodeObj {
+		return fmt.Errorf("expecting an object")
+	}
+
+	st := util.NewStackInf()
+
+	ex, _, err := co.compileArgNode(sel.Ti, sel, &tr, st, arg.Val, true)
+	if err != nil {
+		return err
+	}
+
+	sel.Having.Exp = ex
+	return nil
+}
+
+func (co *Compiler) compileArgOrderBy(sel *Select, arg *graph.Arg, tr trval) error {
 	if arg.Val.Type != graph.NodeObj {
 		return fmt.Errorf("expecting an object")
 	}
@@ -944,6 +1090,9 @@ func (co *Compiler) compileArgOrderBy(sel *Select, arg *graph.Arg) error {
 		if err := setOrderByColName(sel.Ti, &ob, node); err != nil {
 			return err
 		}
+		if tr.mask(ob.Col.Name) != MaskTypeNone {
+			return fmt.Errorf("order by: column masked: %s (%s)", ob.Col.Name, tr.role)
+		}
 		if _, ok := cm[ob.Col.Name]; ok {
 			return fmt.Errorf("duplicate column in order by: %s", ob.Col.Name)
 		}
@@ -957,27 +1106,27 @@ func (co *Compiler) compileArgOrderBy(sel *Select, arg *graph.Arg) error {
 	return nil
 }
 
-func (co *Compiler) compileArgDistinctOn(sel *Select, arg *graph.Arg) error {
+func (co *Compiler) compileArgDistinctOn(sel *Select, arg *graph.Arg, tr trval) error {
 	node := arg.Val
 
 	if node.Type != graph.NodeList && node.Type != graph.NodeStr {
 		return fmt.Errorf("expecting a list of strings or just a string")
 	}
 
+	nodes := node.Children
 	if node.Type == graph.NodeStr {
-		if col, err := sel.Ti.GetColumn(node.Val); err == nil {
-			sel.DistinctOn = append(sel.DistinctOn, col)
-		} else {
-			return err
-		}
+		nodes = []*graph.Node{node}
 	}
 
-	for _, node := range node.Children {
-		if col, err := sel.Ti.GetColumn(node.Val); err == nil {
-			sel.DistinctOn = append(sel.DistinctOn, col)
-		} else {
+	for _, node := range nodes {
+		col, err := sel.Ti.GetColumn(node.Val)
+		if err != nil {
 			return err
 		}
+		if tr.mask(col.Name) != MaskTypeNone {
+			return fmt.Errorf("distinct: column masked: %s (%s)", col.Name, tr.role)
+		}
+		sel.DistinctOn = append(sel.DistinctOn, col)
 	}
 
 	return nil
@@ -1112,7 +1261,7 @@ func compileFilter(ti sdata.DBTableInfo, filter []string) (*Exp, bool, error) {
 			return nil, false, err
 		}
 
-		f, nu, err := co.compileArgNode(ti, st, node, false)
+		f, nu, err := co.compileArgNode(ti, nil, nil, st, node, false)
 		if err != nil {
 			return nil, false, err
 		}
@@ -235,6 +235,85 @@ func TestFragmentsCompile3(t *testing.T) {
 	}
 }
 
+func TestEncryptedColumnFilters(t *testing.T) {
+	di := sdata.GetTestDBInfo()
+
+	for _, v := range []string{"email", "phone"} {
+		col, err := di.GetColumn("users", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		col.Encrypted = true
+		col.Deterministic = (v == "email")
+	}
+
+	schema, err := sdata.NewDBSchema(di, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qcompile, _ := qcode.NewCompiler(schema, qcode.Config{})
+
+	tests := []struct {
+		where string
+		valid bool
+	}{
+		{`{ email: { eq: $email } }`, true},
+		{`{ email: { in: $emails } }`, true},
+		{`{ phone: { is_null: true } }`, true},
+		{`{ email: { eq: "jane@test.com" } }`, false},
+		{`{ email: { like: $email } }`, false},
+		{`{ phone: { eq: $phone } }`, false},
+	}
+
+	for _, v := range tests {
+		_, err := qcompile.Compile([]byte(`query { users(where: `+v.where+`) { id } }`), nil, "user")
+
+		if v.valid && err != nil {
+			t.Errorf("%s: %s", v.where, err)
+		}
+		if !v.valid && err == nil {
+			t.Errorf("%s: expected an error", v.where)
+		}
+	}
+}
+
+func TestRoleAccess(t *testing.T) {
+	qc, _ := qcode.NewCompiler(dbs, qcode.Config{DefaultBlock: true})
+	err := qc.AddRole("user", "products", qcode.TRConfig{
+		Query: qcode.QueryConfig{
+			Columns:          []string{"id", "name"},
+			DisableFunctions: true,
+		},
+		Delete: qcode.DeleteConfig{Block: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qc.IsBlocked("user", "products", qcode.QTQuery) {
+		t.Error("query should not be blocked")
+	}
+	if !qc.IsBlocked("user", "products", qcode.QTDelete) {
+		t.Error("delete should be blocked")
+	}
+	if !qc.IsBlocked("anon", "products", qcode.QTQuery) {
+		t.Error("query should be blocked by default for anon")
+	}
+	if !qc.IsColumnAllowed("user", "products", "name", qcode.QTQuery) {
+		t.Error("column 'name' should be allowed")
+	}
+	if qc.IsColumnAllowed("user", "products", "price", qcode.QTQuery) {
+		t.Error("column 'price' should not be allowed")
+	}
+	if !qc.IsColumnAllowed("user", "products", "price", qcode.QTUpdate) {
+		t.Error("column 'price' should be allowed for updates")
+	}
+	if !qc.IsFuncsBlocked("user", "products") {
+		t.Error("functions should be blocked")
+	}
+}
+
 var gql = []byte(`
 	{products(
 		# returns only 30 items
@@ -0,0 +1,81 @@
+package qcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dosco/graphjin/core/internal/graph"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+// Window is the partition and order of the rows a window function is run over
+type Window struct {
+	Partition []sdata.DBColumn
+	OrderBy   []OrderBy
+}
+
+// windowFunc returns the name and column of a window function field
+// for example rank, lag_price or sum_over_price for a running total
+func windowFunc(ti sdata.DBTableInfo, fname string) (string, string, bool) {
+	// columns with the same name are not window functions
+	if _, err := ti.GetColumn(fname); err == nil {
+		return "", "", false
+	}
+
+	switch fname {
+	case "row_number", "rank", "dense_rank":
+		return fname, "", true
+	}
+
+	switch {
+	case strings.HasPrefix(fname, "lag_"):
+		return "lag", fname[4:], true
+	case strings.HasPrefix(fname, "lead_"):
+		return "lead", fname[5:], true
+	case strings.HasPrefix(fname, "sum_over_"):
+		return "sum", fname[9:], true
+	}
+	return "", "", false
+}
+
+// compileWindowArgs sets the partition and order of the window function
+// and the offset for lag and lead
+func (co *Compiler) compileWindowArgs(ti sdata.DBTableInfo, fn *Function, args []graph.Arg, tr trval) error {
+	ws := Select{Ti: ti}
+
+	for i := range args {
+		arg := &args[i]
+
+		switch arg.Name {
+		case "partition_by", "partition":
+			if err := co.compileArgDistinctOn(&ws, arg, tr); err != nil {
+				return err
+			}
+
+		case "order_by", "orderby", "order":
+			if err := co.compileArgOrderBy(&ws, arg, tr); err != nil {
+				return err
+			}
+
+		case "offset":
+			if fn.Name != "lag" && fn.Name != "lead" {
+				return fmt.Errorf("%s: unknown argument '%s'", fn.Name, arg.Name)
+			}
+			if arg.Val.Type != graph.NodeNum {
+				return argErr(arg.Name, "number")
+			}
+			if n, err := strconv.Atoi(arg.Val.Val); err != nil || n < 0 {
+				return fmt.Errorf("%s: offset must be a positive number", fn.Name)
+			}
+			fn.Arg = arg.Val.Val
+
+		default:
+			return fmt.Errorf("%s: unknown argument '%s'", fn.Name, arg.Name)
+		}
+	}
+
+	fn.Window.Partition = ws.DistinctOn
+	fn.Window.OrderBy = ws.OrderBy
+	return nil
+}
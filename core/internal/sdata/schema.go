@@ -22,20 +22,24 @@ type DBSchema struct {
 	rm  map[string][]DBRel
 	vt  map[string]VirtualTable
 	fm  map[string]DBFunction
+	em  map[string]DBEnum
 }
 
 type DBTableInfo struct {
-	Name       string
-	Type       string
-	IsSingular bool
-	IsAlias    bool
-	Columns    []DBColumn
-	PrimaryCol DBColumn
-	TSVCol     DBColumn
-	Singular   string
-	Plural     string
-	Blocked    bool
-	Schema     *DBSchema
+	Name        string
+	Type        string
+	IsSingular  bool
+	IsAlias     bool
+	Columns     []DBColumn
+	PrimaryCol  DBColumn
+	TSVCol      DBColumn
+	DeletedCol  DBColumn
+	VersionCol  DBColumn
+	Singular    string
+	Plural      string
+	Blocked     bool
+	Description string
+	Schema      *DBSchema
 
 	colMap map[string]int
 }
@@ -80,6 +84,7 @@ func NewDBSchema(info *DBInfo, aliases map[string][]string) (*DBSchema, error) {
 		rm:  make(map[string][]DBRel),
 		vt:  make(map[string]VirtualTable),
 		fm:  make(map[string]DBFunction),
+		em:  make(map[string]DBEnum),
 	}
 
 	for i, t := range info.Tables {
@@ -113,6 +118,10 @@ func NewDBSchema(info *DBInfo, aliases map[string][]string) (*DBSchema, error) {
 		}
 	}
 
+	for _, e := range info.Enums {
+		schema.em[e.Name] = e
+	}
+
 	return schema, nil
 }
 
@@ -125,14 +134,15 @@ func (s *DBSchema) addTableInfo(
 	plural := flect.Pluralize(t.Key)
 
 	ti := DBTableInfo{
-		Name:     t.Name,
-		Type:     t.Type,
-		Columns:  cols,
-		Singular: singular,
-		Plural:   plural,
-		Blocked:  t.Blocked,
-		Schema:   s,
-		colMap:   colmap,
+		Name:        t.Name,
+		Type:        t.Type,
+		Columns:     cols,
+		Singular:    singular,
+		Plural:      plural,
+		Blocked:     t.Blocked,
+		Description: t.Description,
+		Schema:      s,
+		colMap:      colmap,
 	}
 
 	for i := range cols {
@@ -145,6 +155,12 @@ func (s *DBSchema) addTableInfo(
 
 		case c.PrimaryKey:
 			ti.PrimaryCol = cols[i]
+
+		case c.SoftDelete:
+			ti.DeletedCol = cols[i]
+
+		case c.Version:
+			ti.VersionCol = cols[i]
 		}
 
 		colmap[c.Key] = i
@@ -614,6 +630,13 @@ func (s *DBSchema) GetFunctions() map[string]DBFunction {
 	return s.fm
 }
 
+// GetEnum returns the enum type with the name, the name is the
+// same as the type of the columns using it
+func (s *DBSchema) GetEnum(name string) (DBEnum, bool) {
+	e, ok := s.em[name]
+	return e, ok
+}
+
 func getRelName(colName string) string {
 	cn := strings.ToLower(colName)
 
@@ -4,9 +4,13 @@ const postgresColumnInfo = `
 SELECT 
 	col.table_name as table,
 	col.column_name as name,
-	col.data_type as "type",
 	(CASE
-		WHEN col.is_nullable = 'YES' THEN TRUE  
+		WHEN col.data_type IN ('ARRAY', 'USER-DEFINED') THEN
+			pg_catalog.format_type((quote_ident(col.udt_schema) || '.' || quote_ident(col.udt_name))::regtype, NULL)
+		ELSE col.data_type
+	END) as "type",
+	(CASE
+		WHEN col.is_nullable = 'NO' THEN TRUE  
 		ELSE FALSE
 	END) AS notnull,
 	(CASE
@@ -56,7 +60,7 @@ SELECT
 	col.column_name as "column",
   col.data_type as "type",
   (CASE
-		WHEN col.is_nullable = 'YES' THEN TRUE  
+		WHEN col.is_nullable = 'NO' THEN TRUE  
 		ELSE FALSE
 	END) AS notnull,
 	(CASE
@@ -96,3 +100,73 @@ WHERE
 ORDER BY 
 	col.ordinal_position;
 `
+
+const postgresDescriptions = `
+SELECT
+	(CASE WHEN d.objsubid = 0 THEN 'table' ELSE 'column' END) as kind,
+	c.relname as "table",
+	COALESCE(a.attname, '') as name,
+	d.description as description
+FROM
+	pg_catalog.pg_description d
+JOIN
+	pg_catalog.pg_class c ON c.oid = d.objoid
+	AND d.classoid = 'pg_catalog.pg_class'::regclass
+JOIN
+	pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN
+	pg_catalog.pg_attribute a ON a.attrelid = c.oid
+	AND a.attnum = d.objsubid
+WHERE
+	n.nspname = $1
+UNION ALL
+SELECT
+	'function' as kind,
+	'' as "table",
+	p.proname as name,
+	d.description as description
+FROM
+	pg_catalog.pg_description d
+JOIN
+	pg_catalog.pg_proc p ON p.oid = d.objoid
+	AND d.classoid = 'pg_catalog.pg_proc'::regclass
+JOIN
+	pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+WHERE
+	n.nspname = $1;
+`
+
+const mysqlDescriptions = `
+SELECT
+	'table' as kind,
+	t.table_name as "table",
+	'' as name,
+	t.table_comment as description
+FROM
+	information_schema.tables t
+WHERE
+	t.table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+	AND t.table_comment <> ''
+UNION ALL
+SELECT
+	'column' as kind,
+	col.table_name as "table",
+	col.column_name as name,
+	col.column_comment as description
+FROM
+	information_schema.columns col
+WHERE
+	col.table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+	AND col.column_comment <> ''
+UNION ALL
+SELECT
+	'function' as kind,
+	'' as "table",
+	r.routine_name as name,
+	r.routine_comment as description
+FROM
+	information_schema.routines r
+WHERE
+	r.routine_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+	AND r.routine_comment <> '';
+`
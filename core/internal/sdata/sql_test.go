@@ -0,0 +1,23 @@
+package sdata
+
+import (
+	"strings"
+	"testing"
+)
+
+// columns are not null when information_schema reports them as not nullable
+func TestColumnInfoNotNull(t *testing.T) {
+	queries := map[string]string{
+		"postgres": postgresColumnInfo,
+		"mysql":    mysqlColumnInfo,
+	}
+
+	for name, q := range queries {
+		if !strings.Contains(q, "WHEN col.is_nullable = 'NO' THEN TRUE") {
+			t.Errorf("%s: notnull should be true when is_nullable is 'NO'", name)
+		}
+		if strings.Contains(q, "WHEN col.is_nullable = 'YES' THEN TRUE") {
+			t.Errorf("%s: notnull should be false when is_nullable is 'YES'", name)
+		}
+	}
+}
@@ -13,6 +13,7 @@ type DBInfo struct {
 	Tables    []DBTable
 	Columns   [][]DBColumn
 	Functions []DBFunction
+	Enums     []DBEnum
 	VTables   []VirtualTable
 	colMap    map[string]*DBColumn
 }
@@ -24,7 +25,7 @@ type VirtualTable struct {
 	FKeyColumn string
 }
 
-func GetDBInfo(db *sql.DB, dbtype string, blockList []string) (*DBInfo, error) {
+func GetDBInfo(db *sql.DB, dbtype, schema string, blockList []string) (*DBInfo, error) {
 	var err error
 
 	di := &DBInfo{Type: dbtype}
@@ -68,6 +69,19 @@ func GetDBInfo(db *sql.DB, dbtype string, blockList []string) (*DBInfo, error) {
 		return nil, err
 	}
 
+	if dbtype != "mysql" {
+		di.Enums, err = GetEnums(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	descs, err := GetDescriptions(db, dbtype, schema)
+	if err != nil {
+		return nil, err
+	}
+	di.AddDescriptions(descs)
+
 	return di, nil
 }
 
@@ -84,6 +98,15 @@ func (di *DBInfo) AddTable(t DBTable, cols []DBColumn) {
 	di.Columns = append(di.Columns, cols)
 }
 
+func (di *DBInfo) GetTable(table string) (*DBTable, error) {
+	for i, t := range di.Tables {
+		if t.Name == table {
+			return &di.Tables[i], nil
+		}
+	}
+	return nil, fmt.Errorf("table: '%s' not found", table)
+}
+
 func (di *DBInfo) GetColumn(table, column string) (*DBColumn, error) {
 	c, ok := di.colMap[(table + column)]
 	if !ok {
@@ -94,11 +117,12 @@ func (di *DBInfo) GetColumn(table, column string) (*DBColumn, error) {
 }
 
 type DBTable struct {
-	ID      int
-	Name    string
-	Key     string
-	Type    string
-	Blocked bool
+	ID          int
+	Name        string
+	Key         string
+	Type        string
+	Blocked     bool
+	Description string
 }
 
 func GetTables(db *sql.DB) ([]DBTable, error) {
@@ -136,19 +160,39 @@ WHERE
 }
 
 type DBColumn struct {
-	ID         int16
-	Name       string
-	Key        string
-	Type       string
-	Array      bool
-	NotNull    bool
-	PrimaryKey bool
-	UniqueKey  bool
-	FKeySchema string
-	FKeyTable  string
-	FKeyCol    string
-	Blocked    bool
-	Table      string
+	ID            int16
+	Name          string
+	Key           string
+	Type          string
+	Array         bool
+	NotNull       bool
+	PrimaryKey    bool
+	UniqueKey     bool
+	FKeySchema    string
+	FKeyTable     string
+	FKeyCol       string
+	Blocked       bool
+	Encrypted     bool
+	Deterministic bool
+	SoftDelete    bool
+	Version       bool
+	Table         string
+	Description   string
+}
+
+// IsBool returns true if the column holds a boolean value
+func (c *DBColumn) IsBool() bool {
+	switch c.Type {
+	case "boolean", "bool", "tinyint", "tinyint(1)":
+		return true
+	}
+	return false
+}
+
+// IsTimestamp returns true if the column holds a date or time value
+func (c *DBColumn) IsTimestamp() bool {
+	return strings.HasPrefix(c.Type, "timestamp") ||
+		c.Type == "date" || c.Type == "datetime"
 }
 
 func GetColumns(db *sql.DB, dbtype string, tables []string) (
@@ -222,8 +266,9 @@ func GetColumns(db *sql.DB, dbtype string, tables []string) (
 }
 
 type DBFunction struct {
-	Name   string
-	Params []DBFuncParam
+	Name        string
+	Params      []DBFuncParam
+	Description string
 }
 
 type DBFuncParam struct {
@@ -289,6 +334,54 @@ ORDER BY
 	return funcs, nil
 }
 
+type DBEnum struct {
+	Name   string
+	Values []string
+}
+
+func GetEnums(db *sql.DB) ([]DBEnum, error) {
+	sqlStmt := `
+SELECT
+	pg_catalog.format_type(t.oid, NULL) as enum_name,
+	e.enumlabel as enum_value
+FROM
+	pg_catalog.pg_type t
+JOIN
+	pg_catalog.pg_enum e ON e.enumtypid = t.oid
+JOIN
+	pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE
+	n.nspname NOT IN ('information_schema', 'pg_catalog')
+ORDER BY
+	t.oid, e.enumsortorder;`
+
+	rows, err := db.Query(sqlStmt)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching enums: %s", err)
+	}
+	defer rows.Close()
+
+	var enums []DBEnum
+	em := make(map[string]int)
+
+	for rows.Next() {
+		var name, val string
+
+		if err := rows.Scan(&name, &val); err != nil {
+			return nil, err
+		}
+
+		if i, ok := em[name]; ok {
+			enums[i].Values = append(enums[i].Values, val)
+		} else {
+			enums = append(enums, DBEnum{Name: name, Values: []string{val}})
+			em[name] = len(enums) - 1
+		}
+	}
+
+	return enums, nil
+}
+
 func newColMap(tables []DBTable, columns [][]DBColumn) map[string]*DBColumn {
 	cm := make(map[string]*DBColumn, len(tables))
 
@@ -321,3 +414,71 @@ func isInList(val string, s []string) bool {
 	}
 	return false
 }
+
+type DBDescription struct {
+	Kind        string
+	Table       string
+	Name        string
+	Description string
+}
+
+// GetDescriptions returns the comments on the tables, columns and
+// functions in the database schema
+func GetDescriptions(db *sql.DB, dbtype, schema string) ([]DBDescription, error) {
+	var rows *sql.Rows
+	var err error
+
+	switch dbtype {
+	case "mysql":
+		rows, err = db.Query(mysqlDescriptions)
+	default:
+		if schema == "" {
+			schema = "public"
+		}
+		rows, err = db.Query(postgresDescriptions, schema)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error fetching descriptions: %s", err)
+	}
+	defer rows.Close()
+
+	var descs []DBDescription
+
+	for rows.Next() {
+		var d DBDescription
+
+		if err := rows.Scan(&d.Kind, &d.Table, &d.Name, &d.Description); err != nil {
+			return nil, err
+		}
+		descs = append(descs, d)
+	}
+
+	return descs, rows.Err()
+}
+
+// AddDescriptions sets the descriptions of tables, columns and functions
+// from the comments on them in the database
+func (di *DBInfo) AddDescriptions(descs []DBDescription) {
+	fm := make(map[string][]int)
+	for i, f := range di.Functions {
+		fm[f.Name] = append(fm[f.Name], i)
+	}
+
+	for _, d := range descs {
+		switch d.Kind {
+		case "table":
+			if t, err := di.GetTable(d.Table); err == nil {
+				t.Description = d.Description
+			}
+		case "column":
+			if c, err := di.GetColumn(d.Table, d.Name); err == nil {
+				c.Description = d.Description
+			}
+		case "function":
+			for _, i := range fm[d.Name] {
+				di.Functions[i].Description = d.Description
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sdata
+
+import "testing"
+
+func TestAddDescriptions(t *testing.T) {
+	di := GetTestDBInfo()
+	di.Functions = append(di.Functions, DBFunction{Name: "get_price"})
+
+	di.AddDescriptions([]DBDescription{
+		{Kind: "table", Table: "products", Description: "Things we sell"},
+		{Kind: "column", Table: "products", Name: "price", Description: "Price in cents"},
+		{Kind: "function", Name: "get_price", Description: "Current price"},
+		{Kind: "table", Table: "missing", Description: "Not a table"},
+		{Kind: "column", Table: "products", Name: "missing", Description: "Not a column"},
+	})
+
+	tb, err := di.GetTable("products")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.Description != "Things we sell" {
+		t.Errorf("expected table description got '%s'", tb.Description)
+	}
+
+	c, err := di.GetColumn("products", "price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Description != "Price in cents" {
+		t.Errorf("expected column description got '%s'", c.Description)
+	}
+
+	if c, _ := di.GetColumn("products", "name"); c.Description != "" {
+		t.Errorf("expected no description got '%s'", c.Description)
+	}
+
+	if d := di.Functions[len(di.Functions)-1].Description; d != "Current price" {
+		t.Errorf("expected function description got '%s'", d)
+	}
+}
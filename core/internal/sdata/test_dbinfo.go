@@ -76,7 +76,8 @@ func GetTestDBInfo() *DBInfo {
 			DBColumn{ID: 2, Name: "product_id", Type: "bigint", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "products", FKeyCol: "id"},
 			DBColumn{ID: 2, Name: "commenter_id", Type: "bigint", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "users", FKeyCol: "id"},
 			DBColumn{ID: 2, Name: "reply_to_id", Type: "bigint", NotNull: false, PrimaryKey: false, UniqueKey: false, FKeySchema: "public", FKeyTable: "comments", FKeyCol: "id"},
-			DBColumn{ID: 3, Name: "body", Type: "character varying", NotNull: false, PrimaryKey: false, UniqueKey: false}},
+			DBColumn{ID: 3, Name: "body", Type: "character varying", NotNull: false, PrimaryKey: false, UniqueKey: false},
+			DBColumn{ID: 4, Name: "deleted_at", Type: "timestamp without time zone", NotNull: false, PrimaryKey: false, UniqueKey: false}},
 	}
 
 	vTables := []VirtualTable{{
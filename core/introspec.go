@@ -1,11 +1,14 @@
 package core
 
 import (
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/chirino/graphql"
 	"github.com/chirino/graphql/resolvers"
 	"github.com/chirino/graphql/schema"
+	"github.com/dosco/graphjin/core/internal/qcode"
 	"github.com/dosco/graphjin/core/internal/sdata"
 )
 
@@ -16,33 +19,210 @@ var typeMap map[string]string = map[string]string{
 	"smallserial":      "Int",
 	"serial":           "Int",
 	"bigserial":        "Int",
+	"int":              "Int",
+	"int2":             "Int",
+	"int4":             "Int",
+	"int8":             "Int",
+	"tinyint":          "Int",
+	"mediumint":        "Int",
 	"decimal":          "Float",
 	"numeric":          "Float",
 	"real":             "Float",
 	"double precision": "Float",
+	"double":           "Float",
+	"float":            "Float",
+	"float4":           "Float",
+	"float8":           "Float",
 	"money":            "Float",
 	"boolean":          "Boolean",
+	"bool":             "Boolean",
+	"date":             "Date",
+	"uuid":             "UUID",
+	"json":             "JSON",
+	"jsonb":            "JSON",
+	"bytea":            "Bytes",
 }
 
+var validName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// gqlTypeName returns the GraphQL type of a database type, array types
+// return the type of their elements. It's used for introspection and to
+// validate the values of variables.
+func gqlTypeName(sc *sdata.DBSchema, dbType string) string {
+	t := strings.TrimSuffix(dbType, "[]")
+
+	if e, ok := sc.GetEnum(t); ok && validEnum(e) {
+		return enumTypeName(e.Name)
+	}
+
+	// drop type modifiers eg. numeric(7,2) or character varying(255)
+	t = strings.ToLower(t)
+	if i := strings.IndexByte(t, '('); i != -1 {
+		if j := strings.IndexByte(t[i:], ')'); j != -1 {
+			t = strings.TrimSpace(t[:i] + t[i+j+1:])
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(t, "timestamp"), t == "datetime":
+		return "Timestamp"
+	case strings.HasPrefix(t, "time"):
+		return "Time"
+	}
+
+	if v, ok := typeMap[t]; ok {
+		return v
+	}
+	return "String"
+}
+
+// validEnum returns true if the enum values are valid GraphQL enum values
+func validEnum(e sdata.DBEnum) bool {
+	for _, v := range e.Values {
+		if !validName.MatchString(v) || v == "true" || v == "false" || v == "null" {
+			return false
+		}
+	}
+	return len(e.Values) != 0
+}
+
+// enumTypeName returns a valid GraphQL name for the enum,
+// schema qualified or quoted names are not valid
+func enumTypeName(name string) string {
+	var sb strings.Builder
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			sb.WriteRune(c)
+		case c >= '0' && c <= '9' && i != 0:
+			sb.WriteRune(c)
+		case c != '"':
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+func isArrayType(col sdata.DBColumn) bool {
+	return col.Array || strings.HasSuffix(col.Type, "[]")
+}
+
+var aggFuncs = []string{
+	"avg",
+	"count",
+	"max",
+	"min",
+	"sum",
+	"stddev",
+	"stddev_pop",
+	"stddev_samp",
+	"variance",
+	"var_pop",
+	"var_samp",
+}
+
+var timeBuckets = []string{"minute", "hour", "day", "week", "month", "quarter", "year"}
+
+// initGraphQLEgine builds the introspection schemas for the default
+// roles, schemas for other roles are built on first use
 func (gj *GraphJin) initGraphQLEgine() error {
+	for _, role := range []string{"anon", "user"} {
+		if _, err := gj.introEngine(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// introEngine returns the engine used to answer introspection
+// queries for the role
+func (gj *GraphJin) introEngine(role string) (*graphql.Engine, error) {
+	if v, ok := gj.ges.Load(role); ok {
+		return v.(*graphql.Engine), nil
+	}
+
+	engine, err := gj.newGraphQLEngine(role)
+	if err != nil {
+		return nil, err
+	}
+
+	v, _ := gj.ges.LoadOrStore(role, engine)
+	return v.(*graphql.Engine), nil
+}
+
+// introSchema builds the GraphQL schema seen by a role. Tables, columns
+// and operations blocked for the role are left out.
+type introSchema struct {
+	role      string
+	sc        *sdata.DBSchema
+	qc        *qcode.Compiler
+	es        *schema.Schema
+	tables    []sdata.DBTableInfo
+	funcs     []sdata.DBFunction
+	scalar    map[string]bool
+	globalIDs bool
+}
+
+func (gj *GraphJin) newGraphQLEngine(role string) (*graphql.Engine, error) {
 	engine := graphql.New()
-	engineSchema := engine.Schema
-	sc := gj.schema
 
-	if err := engineSchema.Parse(`enum OrderDirection { asc desc }`); err != nil {
-		return err
+	in := &introSchema{
+		role:      role,
+		sc:        gj.schema,
+		qc:        gj.qc,
+		es:        engine.Schema,
+		scalar:    make(map[string]bool),
+		globalIDs: gj.conf.GlobalIDs,
 	}
 
-	gqltype := func(col sdata.DBColumn) schema.Type {
-		typeName := typeMap[strings.ToLower(col.Type)]
-		if typeName == "" {
-			typeName = "String"
+	if err := in.build(); err != nil {
+		return nil, err
+	}
+
+	engine.Resolver = resolvers.Func(func(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
+		resolver := resolvers.MetadataResolver.Resolve(request, next)
+		if resolver != nil {
+			return resolver
 		}
-		var t schema.Type = &schema.TypeName{Name: typeName}
-		if col.NotNull {
-			t = &schema.NonNull{OfType: t}
+		resolver = resolvers.MethodResolver.Resolve(request, next) // needed by the MetadataResolver
+		if resolver != nil {
+			return resolver
 		}
-		return t
+
+		return nil
+	})
+
+	return engine, nil
+}
+
+func (in *introSchema) build() error {
+	err := in.es.Parse(`
+	enum OrderDirection {
+		asc
+		desc
+		asc_nulls_first
+		desc_nulls_first
+		asc_nulls_last
+		desc_nulls_last
+	}
+	enum FindDirection {
+		children
+		parents
+	}
+	scalar Timestamp
+	scalar Date
+	scalar Time
+	scalar UUID
+	scalar JSON
+	scalar Bytes
+	type PageInfo {
+		hasNextPage: Boolean!
+		hasPreviousPage: Boolean!
+		startCursor: String
+		endCursor: String
+	}`)
+	if err != nil {
+		return err
 	}
 
 	query := &schema.Object{
@@ -53,531 +233,1030 @@ func (gj *GraphJin) initGraphQLEgine() error {
 		Name:   "Mutation",
 		Fields: schema.FieldList{},
 	}
-	engineSchema.Types[query.Name] = query
-	engineSchema.Types[mutation.Name] = mutation
-	engineSchema.EntryPoints[schema.Query] = query
-	engineSchema.EntryPoints[schema.Mutation] = mutation
+	subscription := &schema.Object{
+		Name:   "Subscription",
+		Fields: schema.FieldList{},
+	}
+	in.es.Types[query.Name] = query
+	in.es.Types[mutation.Name] = mutation
+	in.es.Types[subscription.Name] = subscription
+	in.es.EntryPoints[schema.Query] = query
+	in.es.EntryPoints[schema.Mutation] = mutation
+	in.es.EntryPoints[schema.Subscription] = subscription
 
-	//validGraphQLIdentifierRegex := regexp.MustCompile(`^[A-Za-z_][A-Za-z_0-9]*$`)
+	fm := in.sc.GetFunctions()
+	fnames := make([]string, 0, len(fm))
+	for k := range fm {
+		fnames = append(fnames, k)
+	}
+	sort.Strings(fnames)
 
-	scalarExpressionTypesNeeded := map[string]bool{}
-	tableNames := sc.GetTableNames()
+	for _, k := range fnames {
+		in.funcs = append(in.funcs, fm[k])
+	}
 
-	var funcs []sdata.DBFunction
-	for _, f := range sc.GetFunctions() {
-		funcs = append(funcs, f)
+	if err := in.loadTables(); err != nil {
+		return err
 	}
 
-	for _, table := range tableNames {
-		ti, err := sc.GetTableInfo(table, "")
-		if err != nil {
+	if in.globalIDs {
+		if err := in.addNodeFields(query, subscription); err != nil {
 			return err
 		}
-		if ti.Blocked {
+	}
+
+	for _, ti := range in.tables {
+		if !in.visible(ti) {
 			continue
 		}
 
-		singularName := ti.Singular
-		// if !validGraphQLIdentifierRegex.MatchString(singularName) {
-		// 	return errors.New("table name is not a valid GraphQL identifier: " + singularName)
-		// }
-		pluralName := ti.Plural
-		// if !validGraphQLIdentifierRegex.MatchString(pluralName) {
-		// 	return errors.New("table name is not a valid GraphQL identifier: " + pluralName)
-		// }
+		if err := in.addTableTypes(ti); err != nil {
+			return err
+		}
+
+		if in.allowed(ti, qcode.QTQuery) {
+			args := in.tableArgs(ti, true)
 
-		outputType := &schema.Object{
-			Name:   singularName + "Output",
-			Fields: schema.FieldList{},
+			for _, obj := range []*schema.Object{query, subscription} {
+				obj.Fields = append(obj.Fields, &schema.Field{
+					Desc: schema.Description{Text: ti.Description},
+					Name: ti.Singular,
+					Type: &schema.TypeName{Name: ti.Singular + "Output"},
+					Args: args,
+				})
+				obj.Fields = append(obj.Fields, &schema.Field{
+					Desc: schema.Description{Text: ti.Description},
+					Name: ti.Plural,
+					Type: listType(ti.Singular + "Output"),
+					Args: args,
+				})
+				obj.Fields = append(obj.Fields, &schema.Field{
+					Desc: schema.Description{Text: ti.Description},
+					Name: ti.Plural + "_connection",
+					Type: connType(ti),
+					Args: in.tableArgs(ti, false),
+				})
+				obj.Fields = append(obj.Fields, &schema.Field{
+					Desc: schema.Description{Text: ti.Description},
+					Name: ti.Plural + "_aggregate",
+					Type: aggregateObjType(ti),
+					Args: in.tableArgs(ti, false),
+				})
+			}
 		}
-		engineSchema.Types[outputType.Name] = outputType
 
-		inputType := &schema.InputObject{
-			Name:   singularName + "Input",
-			Fields: schema.InputValueList{},
+		if args := in.mutationArgs(ti, false); args != nil {
+			mutation.Fields = append(mutation.Fields, &schema.Field{
+				Desc: schema.Description{Text: ti.Description},
+				Name: ti.Singular,
+				Type: &schema.TypeName{Name: ti.Singular + "Output"},
+				Args: args,
+			})
+			mutation.Fields = append(mutation.Fields, &schema.Field{
+				Desc: schema.Description{Text: ti.Description},
+				Name: ti.Plural,
+				Type: listType(ti.Singular + "Output"),
+				Args: in.mutationArgs(ti, true),
+			})
 		}
-		engineSchema.Types[inputType.Name] = inputType
+	}
+
+	if len(mutation.Fields) == 0 {
+		delete(in.es.Types, mutation.Name)
+		delete(in.es.EntryPoints, schema.Mutation)
+	}
+
+	scalars := make([]string, 0, len(in.scalar))
+	for k := range in.scalar {
+		scalars = append(scalars, k)
+	}
+	sort.Strings(scalars)
+
+	for _, typeName := range scalars {
+		in.addExpressionType(typeName)
+	}
+
+	return in.es.ResolveTypes()
+}
+
+// loadTables loads the tables in the schema sorted by name,
+// each table is listed once
+func (in *introSchema) loadTables() error {
+	names := in.sc.GetTableNames()
+	sort.Strings(names)
 
-		orderByType := &schema.InputObject{
-			Name:   singularName + "OrderBy",
-			Fields: schema.InputValueList{},
+	tm := make(map[string]struct{})
+
+	for _, name := range names {
+		ti, err := in.sc.GetTableInfo(name, "")
+		if err != nil {
+			return err
+		}
+		if _, ok := tm[ti.Singular]; ok {
+			continue
 		}
-		engineSchema.Types[orderByType.Name] = orderByType
+		tm[ti.Singular] = struct{}{}
+		in.tables = append(in.tables, ti)
+	}
+	return nil
+}
 
-		for _, t := range tableNames {
-			if _, err := sc.GetRel(t, ti.Name, ""); err != nil {
-				continue
-			}
-			ti1, err := sc.GetTableInfo(t, "")
-			if err != nil {
-				return err
-			}
-			if ti1.Blocked {
-				continue
-			}
-			singularName := ti1.Singular
-			pluralName := ti1.Plural
+// timeBucketCol returns true if the column can be truncated
+// using time bucket fields like created_at_by_day
+func (in *introSchema) timeBucketCol(col sdata.DBColumn) bool {
+	t := strings.ToLower(col.Type)
+	return in.sc.Type() != "mysql" && !isArrayType(col) &&
+		(t == "date" || strings.HasPrefix(t, "timestamp"))
+}
 
-			outputType.Fields = append(outputType.Fields, &schema.Field{
-				Name: singularName,
-				Type: &schema.TypeName{Name: singularName + "Output"},
-			})
+func (in *introSchema) allowed(ti sdata.DBTableInfo, qt qcode.QType) bool {
+	return !ti.Blocked && !in.qc.IsBlocked(in.role, ti.Name, qt)
+}
 
-			outputType.Fields = append(outputType.Fields, &schema.Field{
-				Name: pluralName,
-				Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: singularName + "Output"}}}},
-			})
+// visible returns true if the role can either query or mutate the table
+func (in *introSchema) visible(ti sdata.DBTableInfo) bool {
+	for _, qt := range []qcode.QType{
+		qcode.QTQuery, qcode.QTInsert, qcode.QTUpdate, qcode.QTUpsert, qcode.QTDelete} {
+		if in.allowed(ti, qt) {
+			return true
 		}
+	}
+	return false
+}
 
-		for _, t := range sc.GetAliases(table) {
-			ti1, err := sc.GetTableInfo(t, table)
-			if err != nil {
-				return err
-			}
-			if ti1.Blocked {
-				continue
-			}
+func (in *introSchema) columns(ti sdata.DBTableInfo, qt qcode.QType) []sdata.DBColumn {
+	var cols []sdata.DBColumn
+	for _, col := range ti.Columns {
+		if col.Blocked || !in.qc.IsColumnAllowed(in.role, ti.Name, col.Name, qt) {
+			continue
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
 
-			if ti1.IsSingular {
-				outputType := &schema.Object{
-					Name:   t + "Output",
-					Fields: schema.FieldList{},
-				}
-				engineSchema.Types[outputType.Name] = outputType
+func (in *introSchema) addTableTypes(ti sdata.DBTableInfo) error {
+	singularName := ti.Singular
 
-				inputType := &schema.InputObject{
-					Name:   t + "Input",
-					Fields: schema.InputValueList{},
-				}
-				engineSchema.Types[inputType.Name] = inputType
+	outputType := &schema.Object{
+		Desc:   schema.Description{Text: ti.Description},
+		Name:   singularName + "Output",
+		Fields: schema.FieldList{},
+	}
+	in.es.Types[outputType.Name] = outputType
 
-				orderByType := &schema.InputObject{
-					Name:   t + "OrderBy",
-					Fields: schema.InputValueList{},
-				}
-				engineSchema.Types[orderByType.Name] = orderByType
+	aggregateType := &schema.Object{
+		Name: singularName + "Aggregate",
+		Fields: schema.FieldList{
+			&schema.Field{
+				Desc: schema.Description{Text: "Number of rows"},
+				Name: "count",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+			},
+		},
+	}
+	in.es.Types[aggregateType.Name] = aggregateType
 
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: t,
-					Type: &schema.TypeName{Name: t + "Output"},
-				})
-			} else {
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: t,
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: ti1.Singular + "Output"}}}},
-				})
-			}
-		}
+	orderByType := &schema.InputObject{
+		Name:   singularName + "OrderBy",
+		Fields: schema.InputValueList{},
+	}
+	in.es.Types[orderByType.Name] = orderByType
 
-		expressionTypeName := singularName + "Expression"
-		expressionType := &schema.InputObject{
-			Name: expressionTypeName,
-			Fields: schema.InputValueList{
-				&schema.InputValue{
-					Name: "and",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: expressionTypeName}},
-				},
-				&schema.InputValue{
-					Name: "or",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: expressionTypeName}},
-				},
-				&schema.InputValue{
-					Name: "not",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: expressionTypeName}},
-				},
+	expressionTypeName := singularName + "Expression"
+	expressionType := &schema.InputObject{
+		Name: expressionTypeName,
+		Fields: schema.InputValueList{
+			&schema.InputValue{
+				Name: "and",
+				Type: &schema.TypeName{Name: expressionTypeName},
 			},
-		}
-		engineSchema.Types[expressionType.Name] = expressionType
+			&schema.InputValue{
+				Name: "or",
+				Type: &schema.TypeName{Name: expressionTypeName},
+			},
+			&schema.InputValue{
+				Name: "not",
+				Type: &schema.TypeName{Name: expressionTypeName},
+			},
+		},
+	}
+	in.es.Types[expressionType.Name] = expressionType
 
-		for _, col := range ti.Columns {
-			colName := col.Name
-			if col.Blocked {
-				continue
-			}
+	havingTypeName := singularName + "Having"
+	havingType := &schema.InputObject{
+		Name: havingTypeName,
+		Fields: schema.InputValueList{
+			&schema.InputValue{
+				Name: "and",
+				Type: &schema.TypeName{Name: havingTypeName},
+			},
+			&schema.InputValue{
+				Name: "or",
+				Type: &schema.TypeName{Name: havingTypeName},
+			},
+			&schema.InputValue{
+				Name: "not",
+				Type: &schema.TypeName{Name: havingTypeName},
+			},
+			&schema.InputValue{
+				Name: "count",
+				Type: &schema.TypeName{Name: "IntExpression"},
+			},
+		},
+	}
+	in.es.Types[havingType.Name] = havingType
+	in.scalar["Int"] = true
 
-			colType := gqltype(col)
-			nullableColType := ""
-			if x, ok := colType.(*schema.NonNull); ok {
-				nullableColType = x.OfType.(*schema.TypeName).Name
-			} else {
-				nullableColType = colType.(*schema.TypeName).Name
-			}
+	funcsBlocked := in.qc.IsFuncsBlocked(in.role, ti.Name)
+	globalID := in.hasGlobalID(ti)
+
+	// the id is the global id and the table can be fetched using node
+	if globalID {
+		outputType.InterfaceNames = []string{"Node"}
+		outputType.Fields = append(outputType.Fields, &schema.Field{
+			Desc: schema.Description{Text: "Global id made from the table name and the primary key"},
+			Name: "id",
+			Type: &schema.NonNull{OfType: &schema.TypeName{Name: "ID"}},
+		})
+	}
+
+	for _, col := range in.columns(ti, qcode.QTQuery) {
+		colName := col.Name
+		colType := in.colType(col)
+		nullableColType := in.typeName(col)
+
+		// masked and encrypted columns can't be ordered by or used in
+		// functions and aggregates, encrypted columns can only be
+		// filtered on when they are deterministic
+		masked := in.qc.IsColumnMasked(in.role, ti.Name, colName)
+		plain := !masked && !col.Encrypted
+		filter := !masked && (!col.Encrypted || col.Deterministic)
 
+		if !globalID || colName != "id" {
 			outputType.Fields = append(outputType.Fields, &schema.Field{
+				Desc: schema.Description{Text: col.Description},
 				Name: colName,
 				Type: colType,
 			})
+		}
 
-			for _, f := range funcs {
+		if !funcsBlocked && plain {
+			for _, f := range in.funcs {
 				if col.Type != f.Params[0].Type {
 					continue
 				}
 				outputType.Fields = append(outputType.Fields, &schema.Field{
+					Desc: schema.Description{Text: f.Description},
 					Name: f.Name + "_" + colName,
 					Type: colType,
 				})
 			}
 
-			// If it's a numeric type...
-			if nullableColType == "Float" || nullableColType == "Int" {
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "avg_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "count_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "max_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "min_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "stddev_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "stddev_pop_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "stddev_samp_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "variance_" + colName,
-					Type: colType,
-				})
-				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "var_pop_" + colName,
-					Type: colType,
-				})
+			if !isArrayType(col) {
+				for _, fn := range in.colAggFuncs(nullableColType) {
+					f := &schema.Field{
+						Name: fn + "_" + colName,
+						Type: &schema.TypeName{Name: aggType(fn, nullableColType)},
+						Args: aggArgs(fn),
+					}
+					if fn == "array_agg" {
+						f.Type = &schema.List{OfType: &schema.TypeName{Name: nullableColType}}
+					}
+					outputType.Fields = append(outputType.Fields, f)
+					aggregateType.Fields = append(aggregateType.Fields, f)
+
+					// aggregates with arguments or lists can't be filtered on
+					if f.Args != nil || fn == "array_agg" {
+						continue
+					}
+					in.scalar[aggType(fn, nullableColType)] = true
+					havingType.Fields = append(havingType.Fields, &schema.InputValue{
+						Name: f.Name,
+						Type: &schema.TypeName{Name: aggType(fn, nullableColType) + "Expression"},
+					})
+				}
+			}
+
+			// Timestamps can be truncated to group rows by time
+			if in.timeBucketCol(col) {
+				for _, unit := range timeBuckets {
+					outputType.Fields = append(outputType.Fields, &schema.Field{
+						Desc: schema.Description{Text: "Truncated to the " + unit + " to group rows by"},
+						Name: colName + "_by_" + unit,
+						Type: &schema.TypeName{Name: "Timestamp"},
+					})
+				}
+			}
+
+			if !isArrayType(col) && nullableColType != "JSON" {
+				for _, fn := range []string{"lag", "lead"} {
+					outputType.Fields = append(outputType.Fields, &schema.Field{
+						Desc: schema.Description{Text: "Value of the row at the offset before or after this one in the window"},
+						Name: fn + "_" + colName,
+						Type: &schema.TypeName{Name: nullableColType},
+						Args: windowArgs(ti, true),
+					})
+				}
+			}
+
+			if (nullableColType == "Float" || nullableColType == "Int") && !isArrayType(col) {
 				outputType.Fields = append(outputType.Fields, &schema.Field{
-					Name: "var_samp_" + colName,
-					Type: colType,
+					Desc: schema.Description{Text: "Running total over the rows in the window"},
+					Name: "sum_over_" + colName,
+					Type: &schema.TypeName{Name: "Float"},
+					Args: windowArgs(ti, false),
 				})
 			}
+		}
 
-			inputType.Fields = append(inputType.Fields, &schema.InputValue{
-				Name: colName,
-				Type: colType,
-			})
+		if plain {
 			orderByType.Fields = append(orderByType.Fields, &schema.InputValue{
 				Name: colName,
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "OrderDirection"}},
+				Type: &schema.TypeName{Name: "OrderDirection"},
 			})
+		}
 
-			scalarExpressionTypesNeeded[nullableColType] = true
+		if filter {
+			in.scalar[nullableColType] = true
 
 			expressionType.Fields = append(expressionType.Fields, &schema.InputValue{
 				Name: colName,
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: nullableColType + "Expression"}},
+				Type: &schema.TypeName{Name: nullableColType + "Expression"},
 			})
 		}
+	}
+
+	if !funcsBlocked {
+		for _, fn := range []string{"row_number", "rank", "dense_rank"} {
+			// columns with the same name take precedence
+			if _, err := ti.GetColumn(fn); err == nil {
+				continue
+			}
+			outputType.Fields = append(outputType.Fields, &schema.Field{
+				Desc: schema.Description{Text: "Position of the row in the window"},
+				Name: fn,
+				Type: &schema.TypeName{Name: "Int"},
+				Args: windowArgs(ti, false),
+			})
+		}
+	}
+
+	if err := in.addRelFields(ti, outputType); err != nil {
+		return err
+	}
+
+	in.addConnTypes(ti)
+
+	for _, qt := range []qcode.QType{qcode.QTInsert, qcode.QTUpdate, qcode.QTUpsert} {
+		if in.allowed(ti, qt) {
+			in.addInputType(ti, qt)
+		}
+	}
+
+	return nil
+}
+
+// addRelFields adds the fields for the tables related to this table
+func (in *introSchema) addRelFields(ti sdata.DBTableInfo, outputType *schema.Object) error {
+	for _, ti1 := range in.tables {
+		rel, err := in.sc.GetRel(ti1.Name, ti.Name, "")
+		if err != nil {
+			continue
+		}
+		if !in.allowed(ti1, qcode.QTQuery) {
+			continue
+		}
 
-		outputTypeName := &schema.TypeName{Name: outputType.Name}
-		inputTypeName := &schema.TypeName{Name: inputType.Name}
-		pluralOutputTypeName := &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: outputType.Name}}}}
-		pluralInputTypeName := &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: inputType.Name}}}}
+		args := in.tableArgs(ti1, false)
 
-		args := schema.InputValueList{
+		if rel.Type == sdata.RelRecursive {
+			args = append(args, &schema.InputValue{
+				Desc: schema.Description{Text: "Finds the parents or children of a recursive relationship"},
+				Name: "find",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "FindDirection"}},
+			})
+		}
+
+		outputType.Fields = append(outputType.Fields, &schema.Field{
+			Desc: schema.Description{Text: ti1.Description},
+			Name: ti1.Singular,
+			Type: &schema.TypeName{Name: ti1.Singular + "Output"},
+			Args: args,
+		})
+
+		outputType.Fields = append(outputType.Fields, &schema.Field{
+			Desc: schema.Description{Text: ti1.Description},
+			Name: ti1.Plural,
+			Type: listType(ti1.Singular + "Output"),
+			Args: args,
+		})
+
+		outputType.Fields = append(outputType.Fields, &schema.Field{
+			Desc: schema.Description{Text: ti1.Description},
+			Name: ti1.Plural + "_connection",
+			Type: connType(ti1),
+			Args: args,
+		})
+
+		switch rel.Type {
+		case sdata.RelOneToOne, sdata.RelOneToMany, sdata.RelOneToManyThrough:
+			outputType.Fields = append(outputType.Fields, &schema.Field{
+				Desc: schema.Description{Text: ti1.Description},
+				Name: ti1.Plural + "_aggregate",
+				Type: aggregateObjType(ti1),
+				Args: args,
+			})
+		}
+	}
+
+	aliases := in.sc.GetAliases(ti.Name)
+	sort.Strings(aliases)
+
+	for _, t := range aliases {
+		ti1, err := in.sc.GetTableInfo(t, ti.Name)
+		if err != nil {
+			return err
+		}
+		if !in.allowed(ti1, qcode.QTQuery) {
+			continue
+		}
+
+		if ti1.IsSingular {
+			outputType.Fields = append(outputType.Fields, &schema.Field{
+				Name: t,
+				Type: &schema.TypeName{Name: ti1.Singular + "Output"},
+				Args: in.tableArgs(ti1, false),
+			})
+		} else {
+			outputType.Fields = append(outputType.Fields, &schema.Field{
+				Name: t,
+				Type: listType(ti1.Singular + "Output"),
+				Args: in.tableArgs(ti1, false),
+			})
+		}
+	}
+
+	return nil
+}
+
+// addNodeFields adds the Node interface and the node and nodes fields
+// used to fetch any row by its global id
+func (in *introSchema) addNodeFields(objs ...*schema.Object) error {
+	err := in.es.Parse(`
+	interface Node {
+		id: ID!
+	}`)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		// tables with the same name take precedence
+		if _, err := in.sc.GetTableInfo("node", ""); err != nil {
+			obj.Fields = append(obj.Fields, &schema.Field{
+				Desc: schema.Description{Text: "Fetches a row of any table by its global id"},
+				Name: "node",
+				Type: &schema.TypeName{Name: "Node"},
+				Args: schema.InputValueList{
+					&schema.InputValue{
+						Name: "id",
+						Type: &schema.NonNull{OfType: &schema.TypeName{Name: "ID"}},
+					},
+				},
+			})
+		}
+		if _, err := in.sc.GetTableInfo("nodes", ""); err != nil {
+			obj.Fields = append(obj.Fields, &schema.Field{
+				Desc: schema.Description{Text: "Fetches rows of any table by their global ids, in the same order"},
+				Name: "nodes",
+				Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.TypeName{Name: "Node"}}},
+				Args: schema.InputValueList{
+					&schema.InputValue{
+						Name: "ids",
+						Type: listType("ID"),
+					},
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// hasGlobalID returns true if the role can select the global id of the table
+func (in *introSchema) hasGlobalID(ti sdata.DBTableInfo) bool {
+	pk := ti.PrimaryCol
+	return in.globalIDs && pk.Name != "" && !pk.Blocked &&
+		in.qc.IsColumnAllowed(in.role, ti.Name, pk.Name, qcode.QTQuery)
+}
+
+// addConnTypes adds the types of the Relay style connection
+// used to page through the rows of the table
+func (in *introSchema) addConnTypes(ti sdata.DBTableInfo) {
+	edgeType := &schema.Object{
+		Name: ti.Singular + "Edge",
+		Fields: schema.FieldList{
+			&schema.Field{
+				Name: "cursor",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
+			},
+			&schema.Field{
+				Name: "node",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: ti.Singular + "Output"}},
+			},
+		},
+	}
+	in.es.Types[edgeType.Name] = edgeType
+
+	connectionType := &schema.Object{
+		Name: ti.Singular + "Connection",
+		Fields: schema.FieldList{
+			&schema.Field{
+				Name: "edges",
+				Type: listType(edgeType.Name),
+			},
+			&schema.Field{
+				Name: "pageInfo",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "PageInfo"}},
+			},
+			&schema.Field{
+				Desc: schema.Description{Text: "Number of rows across all the pages"},
+				Name: "totalCount",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+			},
+		},
+	}
+	in.es.Types[connectionType.Name] = connectionType
+}
+
+// addInputType adds the input type for the columns the role
+// can set with the mutation, related tables can be nested
+func (in *introSchema) addInputType(ti sdata.DBTableInfo, qt qcode.QType) {
+	inputType := &schema.InputObject{
+		Name:   inputTypeName(ti, qt),
+		Fields: schema.InputValueList{},
+	}
+	in.es.Types[inputType.Name] = inputType
+
+	for _, col := range in.columns(ti, qt) {
+		t := in.colType(col)
+
+		// updates only set the columns in the input
+		if v, ok := t.(*schema.NonNull); ok && qt == qcode.QTUpdate {
+			t = v.OfType
+		}
+		inputType.Fields = append(inputType.Fields, &schema.InputValue{
+			Desc: schema.Description{Text: col.Description},
+			Name: col.Name,
+			Type: t,
+		})
+	}
+
+	// upserts cannot be nested
+	if qt == qcode.QTUpsert {
+		return
+	}
+
+	for _, ti1 := range in.tables {
+		if _, err := in.sc.GetRel(ti1.Name, ti.Name, ""); err != nil {
+			continue
+		}
+		if !in.allowed(ti1, qt) {
+			continue
+		}
+		inputType.Fields = append(inputType.Fields, &schema.InputValue{
+			Name: ti1.Singular,
+			Type: &schema.TypeName{Name: inputTypeName(ti1, qt)},
+		})
+		inputType.Fields = append(inputType.Fields, &schema.InputValue{
+			Name: ti1.Plural,
+			Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: inputTypeName(ti1, qt)}}},
+		})
+	}
+}
+
+// tableArgs returns the arguments used to select rows from the table,
+// the id argument is only valid at the root of the query
+func (in *introSchema) tableArgs(ti sdata.DBTableInfo, root bool) schema.InputValueList {
+	args := schema.InputValueList{
+		&schema.InputValue{
+			Desc: schema.Description{Text: "To sort or ordering results just use the order_by argument. This can be combined with where, search, etc to build complex queries to fit your needs."},
+			Name: "order_by",
+			Type: &schema.TypeName{Name: ti.Singular + "OrderBy"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Filters the rows returned"},
+			Name: "where",
+			Type: &schema.TypeName{Name: ti.Singular + "Expression"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Filters the groups of rows using the aggregates"},
+			Name: "having",
+			Type: &schema.TypeName{Name: ti.Singular + "Having"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Returns only the first row of each set of rows with the same values for these columns"},
+			Name: "distinct",
+			Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}}},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Maximum number of rows returned"},
+			Name: "limit",
+			Type: &schema.TypeName{Name: "Int"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Number of rows to skip"},
+			Name: "offset",
+			Type: &schema.TypeName{Name: "Int"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Number of rows to return after the cursor"},
+			Name: "first",
+			Type: &schema.TypeName{Name: "Int"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Number of rows to return before the cursor"},
+			Name: "last",
+			Type: &schema.TypeName{Name: "Int"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Cursor to fetch the rows before"},
+			Name: "before",
+			Type: &schema.TypeName{Name: "String"},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Cursor to fetch the rows after"},
+			Name: "after",
+			Type: &schema.TypeName{Name: "String"},
+		},
+	}
+
+	if root && ti.PrimaryCol.Name != "" {
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: "Finds the record by the primary key"},
+			Name: "id",
+			Type: &schema.TypeName{Name: in.typeName(ti.PrimaryCol)},
+		})
+	}
+
+	// the search index can include the masked columns
+	if ti.TSVCol.Name != "" && !in.qc.HasMaskedColumns(in.role, ti.Name) {
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: "Performs full text search using a TSV index"},
+			Name: "search",
+			Type: &schema.TypeName{Name: "String"},
+		})
+	}
+
+	if ti.DeletedCol.Name != "" {
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: "Includes the rows marked as deleted"},
+			Name: "with_deleted",
+			Type: &schema.TypeName{Name: "Boolean"},
+		})
+	}
+
+	return args
+}
+
+// mutationArgs returns the arguments of a mutation on the table for
+// the operations allowed, nil if all of them are blocked. Plural fields
+// take a list of inputs.
+func (in *introSchema) mutationArgs(ti sdata.DBTableInfo, plural bool) schema.InputValueList {
+	var args schema.InputValueList
+
+	for _, v := range []struct {
+		name string
+		qt   qcode.QType
+		desc string
+	}{
+		{"insert", qcode.QTInsert, "Inserts new rows"},
+		{"update", qcode.QTUpdate, "Updates the rows selected by id or where"},
+		{"upsert", qcode.QTUpsert, "Inserts new rows or updates the rows selected by id or where"},
+	} {
+		if !in.allowed(ti, v.qt) {
+			continue
+		}
+
+		var t schema.Type = &schema.TypeName{Name: inputTypeName(ti, v.qt)}
+		if plural {
+			t = &schema.List{OfType: &schema.NonNull{OfType: t}}
+		}
+
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: v.desc},
+			Name: v.name,
+			Type: t,
+		})
+	}
+
+	if in.allowed(ti, qcode.QTDelete) {
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: "Deletes the rows selected by id or where"},
+			Name: "delete",
+			Type: &schema.TypeName{Name: "Boolean"},
+		})
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	return append(in.tableArgs(ti, true), args...)
+}
+
+func (in *introSchema) addExpressionType(typeName string) {
+	expressionType := &schema.InputObject{
+		Name: typeName + "Expression",
+		Fields: schema.InputValueList{
 			&schema.InputValue{
-				Desc: schema.Description{Text: "To sort or ordering results just use the order_by argument. This can be combined with where, search, etc to build complex queries to fit your needs."},
-				Name: "order_by",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: orderByType.Name}},
+				Name: "eq",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "where",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: expressionType.Name}},
+				Name: "equals",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "limit",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+				Name: "neq",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "offset",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+				Name: "not_equals",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "first",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+				Name: "gt",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "last",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Int"}},
+				Name: "greater_than",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "lt",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "lesser_than",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "gte",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "greater_or_equals",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "lte",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "lesser_or_equals",
+				Type: &schema.TypeName{Name: typeName},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "before",
+				Name: "in",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
+			},
+			&schema.InputValue{
+				Name: "nin",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
+			},
+			&schema.InputValue{
+				Name: "not_in",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
+			},
+
+			&schema.InputValue{
+				Name: "like",
 				Type: &schema.TypeName{Name: "String"},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "after",
+				Name: "nlike",
 				Type: &schema.TypeName{Name: "String"},
 			},
-		}
-
-		if ti.PrimaryCol.Name != "" {
-			t := gqltype(ti.PrimaryCol)
-			if _, ok := t.(*schema.NonNull); !ok {
-				t = &schema.NonNull{OfType: t}
-			}
-			args = append(args, &schema.InputValue{
-				Desc: schema.Description{Text: "Finds the record by the primary key"},
-				Name: "id",
-				Type: t,
-			})
-		}
-
-		if ti.TSVCol.Name != "" {
-			args = append(args, &schema.InputValue{
-				Desc: schema.Description{Text: "Performs full text search using a TSV index"},
-				Name: "search",
-				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-			})
-		}
-
-		query.Fields = append(query.Fields, &schema.Field{
-			Desc: schema.Description{Text: ""},
-			Name: singularName,
-			Type: outputTypeName,
-			Args: args,
-		})
-		query.Fields = append(query.Fields, &schema.Field{
-			Desc: schema.Description{Text: ""},
-			Name: pluralName,
-			Type: pluralOutputTypeName,
-			Args: args,
-		})
-
-		mutationArgs := append(args, schema.InputValueList{
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "insert",
-				Type: inputTypeName,
+				Name: "not_like",
+				Type: &schema.TypeName{Name: "String"},
 			},
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "update",
-				Type: inputTypeName,
+				Name: "ilike",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "nilike",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "not_ilike",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "similar",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "nsimilar",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "not_similar",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "regex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "nregex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "not_regex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "iregex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "niregex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "not_iregex",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "has_key",
+				Type: &schema.TypeName{Name: typeName},
+			},
+			&schema.InputValue{
+				Name: "has_key_any",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
 			},
-
 			&schema.InputValue{
-				Desc: schema.Description{Text: ""},
-				Name: "upsert",
-				Type: inputTypeName,
+				Name: "has_key_all",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
 			},
-		}...)
+			&schema.InputValue{
+				Name: "contains",
+				Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}},
+			},
+			&schema.InputValue{
+				Name: "contained_in",
+				Type: &schema.TypeName{Name: "String"},
+			},
+			&schema.InputValue{
+				Name: "is_null",
+				Type: &schema.TypeName{Name: "Boolean"},
+			},
+		},
+	}
+	in.es.Types[expressionType.Name] = expressionType
+}
 
-		mutation.Fields = append(mutation.Fields, &schema.Field{
-			Name: singularName,
-			Args: mutationArgs,
-			Type: outputType,
-		})
-		mutation.Fields = append(mutation.Fields, &schema.Field{
-			Name: pluralName,
-			Args: append(mutationArgs, schema.InputValueList{
-				&schema.InputValue{
-					Desc: schema.Description{Text: ""},
-					Name: "inserts",
-					Type: pluralInputTypeName,
-				},
-				&schema.InputValue{
-					Desc: schema.Description{Text: ""},
-					Name: "updates",
-					Type: pluralInputTypeName,
-				},
-				&schema.InputValue{
-					Desc: schema.Description{Text: ""},
-					Name: "upserts",
-					Type: pluralInputTypeName,
-				},
-			}...),
-			Type: outputType,
-		})
+func inputTypeName(ti sdata.DBTableInfo, qt qcode.QType) string {
+	switch qt {
+	case qcode.QTUpdate:
+		return ti.Singular + "UpdateInput"
+	case qcode.QTUpsert:
+		return ti.Singular + "UpsertInput"
+	default:
+		return ti.Singular + "InsertInput"
 	}
+}
 
-	for typeName := range scalarExpressionTypesNeeded {
-		expressionType := &schema.InputObject{
-			Name: typeName + "Expression",
-			Fields: schema.InputValueList{
-				&schema.InputValue{
-					Name: "eq",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "equals",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "neq",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "not_equals",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "gt",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "greater_than",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "lt",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "lesser_than",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "gte",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "greater_or_equals",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "lte",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "lesser_or_equals",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "in",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
-				&schema.InputValue{
-					Name: "nin",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
-				&schema.InputValue{
-					Name: "not_in",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
+// typeName returns the GraphQL type of the column, enum
+// types are added to the schema when first used
+func (in *introSchema) typeName(col sdata.DBColumn) string {
+	name := gqlTypeName(in.sc, col.Type)
 
-				&schema.InputValue{
-					Name: "like",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "nlike",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "not_like",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "ilike",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "nilike",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "not_ilike",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "similar",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "nsimilar",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "not_similar",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "regex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "nregex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "not_regex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "iregex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "niregex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "not_iregex",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "has_key",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}},
-				},
-				&schema.InputValue{
-					Name: "has_key_any",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
-				&schema.InputValue{
-					Name: "has_key_all",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
-				&schema.InputValue{
-					Name: "contains",
-					Type: &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: typeName}}}},
-				},
-				&schema.InputValue{
-					Name: "contained_in",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}},
-				},
-				&schema.InputValue{
-					Name: "is_null",
-					Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Boolean"}},
-				},
-			},
+	if _, ok := in.es.Types[name]; ok {
+		return name
+	}
+
+	if e, ok := in.sc.GetEnum(strings.TrimSuffix(col.Type, "[]")); ok {
+		et := &schema.Enum{Name: name}
+		for _, v := range e.Values {
+			et.Values = append(et.Values, &schema.EnumValue{Name: v})
 		}
-		engineSchema.Types[expressionType.Name] = expressionType
+		in.es.Types[name] = et
 	}
+	return name
+}
 
-	if err := engineSchema.ResolveTypes(); err != nil {
-		return err
+func (in *introSchema) colType(col sdata.DBColumn) schema.Type {
+	var t schema.Type = &schema.TypeName{Name: in.typeName(col)}
+	if isArrayType(col) {
+		t = &schema.List{OfType: t}
 	}
+	if col.NotNull {
+		t = &schema.NonNull{OfType: t}
+	}
+	return t
+}
 
-	engine.Resolver = resolvers.Func(func(request *resolvers.ResolveRequest, next resolvers.Resolution) resolvers.Resolution {
-		resolver := resolvers.MetadataResolver.Resolve(request, next)
-		if resolver != nil {
-			return resolver
-		}
-		resolver = resolvers.MethodResolver.Resolve(request, next) // needed by the MetadataResolver
-		if resolver != nil {
-			return resolver
-		}
+// aggType returns the type of the value returned by the aggregate function
+func aggType(fn, typeName string) string {
+	switch fn {
+	case "count", "count_distinct":
+		return "Int"
+	case "min", "max", "mode", "percentile_disc":
+		return typeName
+	case "string_agg":
+		return "String"
+	case "bool_and", "bool_or":
+		return "Boolean"
+	default:
+		return "Float"
+	}
+}
+
+// colAggFuncs returns the aggregate functions for columns of the type
+func (in *introSchema) colAggFuncs(typeName string) []string {
+	var funcs []string
+	mysql := (in.sc.Type() == "mysql")
 
+	switch typeName {
+	case "Float", "Int":
+		funcs = append(funcs, aggFuncs...)
+		if !mysql {
+			funcs = append(funcs, "percentile_cont", "percentile_disc")
+		}
+	case "String":
+		funcs = append(funcs, "string_agg")
+	case "Boolean":
+		funcs = append(funcs, "bool_and", "bool_or")
+	case "JSON":
 		return nil
-	})
+	}
+
+	funcs = append(funcs, "count_distinct", "array_agg")
+	if !mysql {
+		funcs = append(funcs, "mode")
+	}
+	return funcs
+}
 
-	gj.ge = engine
+// windowArgs returns the arguments of the window functions
+func windowArgs(ti sdata.DBTableInfo, offset bool) schema.InputValueList {
+	args := schema.InputValueList{
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Columns to split the rows into windows by"},
+			Name: "partition_by",
+			Type: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: "String"}}},
+		},
+		&schema.InputValue{
+			Desc: schema.Description{Text: "Order of the rows in the window"},
+			Name: "order_by",
+			Type: &schema.TypeName{Name: ti.Singular + "OrderBy"},
+		},
+	}
+	if offset {
+		args = append(args, &schema.InputValue{
+			Desc: schema.Description{Text: "Number of rows before or after, defaults to 1"},
+			Name: "offset",
+			Type: &schema.TypeName{Name: "Int"},
+		})
+	}
+	return args
+}
+
+// aggArgs returns the arguments of the aggregate function
+func aggArgs(fn string) schema.InputValueList {
+	switch fn {
+	case "percentile_cont", "percentile_disc":
+		return schema.InputValueList{
+			&schema.InputValue{
+				Desc: schema.Description{Text: "Fraction between 0 and 1, 0.5 is the median"},
+				Name: "fraction",
+				Type: &schema.NonNull{OfType: &schema.TypeName{Name: "Float"}},
+			},
+		}
+	case "string_agg":
+		return schema.InputValueList{
+			&schema.InputValue{
+				Desc: schema.Description{Text: "Separator between the values, defaults to a comma"},
+				Name: "separator",
+				Type: &schema.TypeName{Name: "String"},
+			},
+		}
+	}
 	return nil
 }
+
+func listType(name string) schema.Type {
+	return &schema.NonNull{OfType: &schema.List{OfType: &schema.NonNull{OfType: &schema.TypeName{Name: name}}}}
+}
+
+func aggregateObjType(ti sdata.DBTableInfo) schema.Type {
+	return &schema.NonNull{OfType: &schema.TypeName{Name: ti.Singular + "Aggregate"}}
+}
+
+func connType(ti sdata.DBTableInfo) schema.Type {
+	return &schema.NonNull{OfType: &schema.TypeName{Name: ti.Singular + "Connection"}}
+}
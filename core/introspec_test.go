@@ -0,0 +1,255 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chirino/graphql/schema"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func newIntroTestGJ(t *testing.T) *GraphJin {
+	conf := &Config{
+		DisableAllowList: true,
+		SecretKey:        "secret",
+		Tables: []Table{
+			{Name: "users", Columns: []Column{
+				{Name: "email", Encrypted: true},
+				{Name: "full_name", Deterministic: true},
+			}},
+		},
+		Roles: []Role{
+			{Name: "anon", Tables: []RoleTable{{
+				Name:   "products",
+				Query:  &Query{Columns: []string{"id", "name"}},
+				Insert: &Insert{Block: true},
+				Update: &Update{Block: true},
+				Upsert: &Upsert{Block: true},
+				Delete: &Delete{Block: true},
+			}}},
+			{Name: "user", Tables: []RoleTable{{
+				Name:  "products",
+				Query: &Query{Masks: map[string]string{"name": "last4"}},
+			}}},
+		},
+	}
+
+	gj, err := newGraphJin(conf, nil, sdata.GetTestDBInfo())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gj
+}
+
+// introFields returns the names of the fields of a type in the
+// schema seen by the role
+func introFields(t *testing.T, gj *GraphJin, role, typeName string) []string {
+	ge, err := gj.introEngine(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	switch v := ge.Schema.Types[typeName].(type) {
+	case *schema.Object:
+		for _, f := range v.Fields {
+			names = append(names, f.Name)
+		}
+	case *schema.InputObject:
+		for _, f := range v.Fields {
+			names = append(names, f.Name)
+		}
+	case nil:
+		t.Fatalf("%s: type not found: %s", role, typeName)
+	}
+	return names
+}
+
+// introArgs returns the names of the arguments of a field of the
+// mutation type, nil if the field is not found
+func introArgs(t *testing.T, gj *GraphJin, role, field string) []string {
+	ge, err := gj.introEngine(role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, ok := ge.Schema.EntryPoints[schema.Mutation].(*schema.Object)
+	if !ok {
+		return nil
+	}
+
+	for _, f := range obj.Fields {
+		if f.Name != field {
+			continue
+		}
+		names := []string{}
+		for _, a := range f.Args {
+			names = append(names, a.Name)
+		}
+		return names
+	}
+	return nil
+}
+
+func hasName(names []string, name string) bool {
+	for _, v := range names {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIntrospectionBlockedColumns(t *testing.T) {
+	gj := newIntroTestGJ(t)
+
+	for _, typeName := range []string{"productOutput", "productExpression", "productOrderBy"} {
+		if hasName(introFields(t, gj, "anon", typeName), "price") {
+			t.Errorf("anon: expected price to be hidden in %s", typeName)
+		}
+		if !hasName(introFields(t, gj, "user", typeName), "price") {
+			t.Errorf("user: expected price in %s", typeName)
+		}
+	}
+
+	if hasName(introFields(t, gj, "anon", "productAggregate"), "max_price") {
+		t.Error("anon: expected no aggregates of price")
+	}
+
+	if hasName(introFields(t, gj, "anon", "productHaving"), "max_price") {
+		t.Error("anon: expected no having filters on price")
+	}
+}
+
+func TestIntrospectionMaskedColumns(t *testing.T) {
+	gj := newIntroTestGJ(t)
+
+	// the masked value can be selected but not filtered or ordered on
+	if !hasName(introFields(t, gj, "user", "productOutput"), "name") {
+		t.Error("expected the masked column to be selected")
+	}
+
+	for _, typeName := range []string{
+		"productExpression", "productOrderBy", "productAggregate", "productHaving", "productOutput"} {
+		for _, f := range introFields(t, gj, "user", typeName) {
+			if strings.HasSuffix(f, "_name") || (f == "name" && typeName != "productOutput") {
+				t.Errorf("expected the masked column to be left out of %s: %s", typeName, f)
+			}
+		}
+	}
+
+	// the search index can include the masked columns
+	if args := introArgs(t, gj, "user", "products"); hasName(args, "search") {
+		t.Error("expected no search argument on a table with masked columns")
+	}
+}
+
+func TestIntrospectionEncryptedColumns(t *testing.T) {
+	gj := newIntroTestGJ(t)
+
+	fields := introFields(t, gj, "user", "userExpression")
+
+	if hasName(fields, "email") {
+		t.Error("expected the encrypted column to be left out of the filters")
+	}
+
+	// deterministic encrypted columns can be matched on
+	if !hasName(fields, "full_name") {
+		t.Error("expected the deterministic encrypted column in the filters")
+	}
+
+	for _, typeName := range []string{"userOrderBy", "userAggregate", "userHaving"} {
+		for _, f := range introFields(t, gj, "user", typeName) {
+			if strings.HasSuffix(f, "email") || strings.HasSuffix(f, "full_name") {
+				t.Errorf("expected the encrypted columns to be left out of %s: %s", typeName, f)
+			}
+		}
+	}
+}
+
+func TestIntrospectionMutationArgs(t *testing.T) {
+	gj := newIntroTestGJ(t)
+
+	if args := introArgs(t, gj, "anon", "products"); args != nil {
+		t.Errorf("anon: expected no products mutation got %v", args)
+	}
+
+	args := introArgs(t, gj, "user", "products")
+
+	for _, v := range []string{"insert", "update", "upsert", "delete", "where", "id"} {
+		if !hasName(args, v) {
+			t.Errorf("user: expected the %s argument in %v", v, args)
+		}
+	}
+
+	// the input takes every column including the masked one
+	fields := introFields(t, gj, "user", "productInsertInput")
+
+	for _, v := range []string{"name", "price"} {
+		if !hasName(fields, v) {
+			t.Errorf("user: expected %s in the insert input got %v", v, fields)
+		}
+	}
+}
+
+// sdlType returns the definition of the type in the schema
+func sdlType(sdl, def string) string {
+	i := strings.Index(sdl, def+" {")
+	if i == -1 {
+		return ""
+	}
+	j := strings.Index(sdl[i:], "}")
+	return sdl[i : i+j+1]
+}
+
+func TestSchemaSDL(t *testing.T) {
+	gj := newIntroTestGJ(t)
+
+	anon, err := gj.SchemaSDL("anon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := gj.SchemaSDL("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, def := range []string{"type Query", "type Subscription", "type productOutput", "input productExpression"} {
+		if sdlType(anon, def) == "" {
+			t.Errorf("anon: expected '%s' in the schema", def)
+		}
+	}
+
+	// the anon role can only select the id and name of products
+	// and can't change them
+	if v := sdlType(anon, "type productOutput"); !strings.Contains(v, "\n  name:") || strings.Contains(v, "\n  price:") {
+		t.Errorf("anon: expected only the allowed columns got %s", v)
+	}
+
+	if v := sdlType(anon, "type Mutation"); strings.Contains(v, "\n  products(") {
+		t.Errorf("anon: expected no products mutation got %s", v)
+	}
+
+	if v := sdlType(user, "type productOutput"); !strings.Contains(v, "\n  price:") {
+		t.Errorf("user: expected the price column got %s", v)
+	}
+
+	if v := sdlType(user, "type Mutation"); !strings.Contains(v, "\n  products(") {
+		t.Errorf("user: expected the products mutation got %s", v)
+	}
+
+	// the masked column is selected but not filtered on
+	if v := sdlType(user, "input productExpression"); strings.Contains(v, "\n  name:") {
+		t.Errorf("user: expected no filter on the masked column got %s", v)
+	}
+
+	if v, err := gj.SchemaSDL("user"); err != nil || v != user {
+		t.Error("expected the same schema each time")
+	}
+
+	if _, err := gj.SchemaSDL("admin"); err == nil {
+		t.Error("expected an error for a role that's not defined")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/dosco/graphjin/core/internal/allow"
+	"github.com/dosco/graphjin/core/internal/psql"
 	"github.com/dosco/graphjin/core/internal/qcode"
 )
 
@@ -24,6 +25,7 @@ type rquery struct {
 	name  string
 	query []byte
 	vars  []byte
+	roles []string
 }
 
 // nolint: errcheck
@@ -38,30 +40,39 @@ func (gj *GraphJin) prepareRoleStmt() error {
 
 	w := &bytes.Buffer{}
 
-	io.WriteString(w, `SELECT (CASE WHEN EXISTS (`)
-	gj.roleStmtMD.RenderVar(w, gj.conf.RolesQuery)
+	io.WriteString(w, `SELECT `)
+	gj.renderRoleMatch(w, &gj.roleStmtMD)
+	io.WriteString(w, ` FROM (VALUES (1)) AS "_sg_auth_filler" LIMIT 1; `)
+
+	gj.roleStmt = w.String()
+
+	return nil
+}
+
+// renderRoleMatch renders the roles matched by the roles_query as a comma
+// separated list, it's 'user' when none match and 'anon' when the query
+// returns no rows. It's used to find the role for both queries and mutations.
+func (gj *GraphJin) renderRoleMatch(w *bytes.Buffer, md *psql.Metadata) {
+	io.WriteString(w, `(CASE WHEN EXISTS (`)
+	md.RenderVar(w, gj.conf.RolesQuery)
 	io.WriteString(w, `) THEN `)
 
-	io.WriteString(w, `(SELECT (CASE`)
+	io.WriteString(w, `(SELECT COALESCE(NULLIF(CONCAT_WS(','`)
 	for _, role := range gj.conf.Roles {
 		if role.Match == "" {
 			continue
 		}
-		io.WriteString(w, ` WHEN `)
+		io.WriteString(w, `, (CASE WHEN `)
 		io.WriteString(w, role.Match)
 		io.WriteString(w, ` THEN '`)
 		io.WriteString(w, role.Name)
-		io.WriteString(w, `'`)
+		io.WriteString(w, `' END)`)
 	}
 
-	io.WriteString(w, ` ELSE 'user' END) FROM (`)
-	gj.roleStmtMD.RenderVar(w, gj.conf.RolesQuery)
+	io.WriteString(w, `), ''), 'user') FROM (`)
+	md.RenderVar(w, gj.conf.RolesQuery)
 	io.WriteString(w, `) AS "_sg_auth_roles_query" LIMIT 1) `)
-	io.WriteString(w, `ELSE 'anon' END) FROM (VALUES (1)) AS "_sg_auth_filler" LIMIT 1; `)
-
-	gj.roleStmt = w.String()
-
-	return nil
+	io.WriteString(w, `ELSE 'anon' END)`)
 }
 
 func (gj *GraphJin) initAllowList() error {
@@ -98,6 +109,7 @@ func (gj *GraphJin) initAllowList() error {
 			name:  v.Name,
 			query: []byte(v.Query),
 			vars:  []byte(v.Vars),
+			roles: v.Roles,
 		}
 
 		switch q.op {
@@ -513,6 +513,162 @@ func Example_queryWithAggregationBlockedColumn() {
 	// Output: column blocked: sum (anon)
 }
 
+func Example_queryWithExtendedRoleBlockedColumn() {
+	gql := `query {
+		products {
+			sum_price
+		}
+	}`
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true}
+	conf.Roles = []core.Role{{Name: "anon", Extends: []string{"user"}}}
+
+	err := conf.AddRoleTable("user", "products", core.Query{
+		Columns: []string{"id", "name"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	res, err := gj.GraphQL(context.Background(), gql, nil)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: column blocked: sum (anon)
+}
+
+func Example_queryWithMaskedColumns() {
+	gql := `query {
+		users(limit: 2) {
+			id
+			email
+			stripe_id
+		}
+	}`
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true}
+	err := conf.AddRoleTable("anon", "users", core.Query{
+		Masks: map[string]string{"email": "email", "stripe_id": "last4"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	res, err := gj.GraphQL(context.Background(), gql, nil)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: {"users": [{"id": 1, "email": "u***@test.com", "stripe_id": "***********1001"}, {"id": 2, "email": "u***@test.com", "stripe_id": "***********1002"}]}
+}
+
+func Example_queryWithEncryptedColumnFilter() {
+	gql := `query {
+		users(where: { stripe_id: { eq: $stripe_id } }) {
+			id
+		}
+	}`
+
+	vars := json.RawMessage(`{ "stripe_id": "payment_id_1001" }`)
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true, SecretKey: "not_a_secret"}
+	conf.Tables = []core.Table{{
+		Name:    "users",
+		Columns: []core.Column{{Name: "stripe_id", Encrypted: true}},
+	}}
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	res, err := gj.GraphQL(context.Background(), gql, vars)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: [Where] column is encrypted: stripe_id
+}
+
+func Example_queryWithMultipleRolesBlockedColumn() {
+	gql := `query {
+		products {
+			sum_price
+		}
+	}`
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true}
+	err := conf.AddRoleTable("user", "products", core.Query{
+		Columns: []string{"id", "name"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = conf.AddRoleTable("editor", "products", core.Query{
+		Columns: []string{"id", "description"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx := context.WithValue(context.Background(), core.UserIDKey, 3)
+	ctx = context.WithValue(ctx, core.UserRoleKey, []string{"user", "editor"})
+
+	res, err := gj.GraphQL(ctx, gql, nil)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: column blocked: sum (editor,user)
+}
+
+func Example_queryWithActiveRoleNotAllowed() {
+	gql := `query {
+		products {
+			id
+		}
+	}`
+
+	conf := &core.Config{DBType: dbType, DisableAllowList: true}
+	gj, err := core.NewGraphJin(conf, db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx := context.WithValue(context.Background(), core.UserIDKey, 3)
+	ctx = context.WithValue(ctx, core.UserRoleKey, []string{"user"})
+	ctx = context.WithValue(ctx, core.ActiveRoleKey, "admin")
+
+	res, err := gj.GraphQL(ctx, gql, nil)
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		fmt.Println(string(res.Data))
+	}
+	// Output: active role not allowed: admin
+}
+
 func Example_queryWithFunctionsBlocked() {
 	gql := `query {
 		products {
@@ -946,6 +1102,70 @@ func Example_queryWithCursorPagination() {
 	// Output: [{"name": "Product 100"}, {"name": "Product 99"}, {"name": "Product 98"}]
 }
 
+func Example_queryWithCursorAfterKeyRotation() {
+	gql := `query {
+		Products(
+			where: { id: { lesser_or_equals: 100 } }
+			first: 3
+			after: $cursor
+			order_by: { price: desc }) {
+			Name
+		}
+		products_cursor
+	}`
+
+	type result struct {
+		Products json.RawMessage `json:"products"`
+		Cursor   string          `json:"products_cursor"`
+	}
+
+	query := func(conf *core.Config, cursor string) (result, error) {
+		var val result
+
+		gj, err := core.NewGraphJin(conf, db)
+		if err != nil {
+			return val, err
+		}
+
+		vars := json.RawMessage(`{"cursor": null}`)
+		if cursor != "" {
+			vars = json.RawMessage(`{"cursor": "` + cursor + `"}`)
+		}
+
+		res, err := gj.GraphQL(context.Background(), gql, vars)
+		if err != nil {
+			return val, err
+		}
+
+		err = json.Unmarshal(res.Data, &val)
+		return val, err
+	}
+
+	val, err := query(&core.Config{
+		DBType:           dbType,
+		DisableAllowList: true,
+		SecretKey:        "old_secret_key",
+	}, "")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	val, err = query(&core.Config{
+		DBType:           dbType,
+		DisableAllowList: true,
+		SecretKey:        "new_secret_key",
+		SecretKeys:       []string{"old_secret_key"},
+	}, val.Cursor)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(string(val.Products))
+	// Output: [{"name": "Product 97"}, {"name": "Product 96"}, {"name": "Product 95"}]
+}
+
 func Example_queryWithJsonColumn() {
 	gql := `query {
 		user {
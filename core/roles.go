@@ -0,0 +1,600 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// checkRoleExtends ensures that all roles being extended exist and that there
+// are no cycles in the role inheritance chain
+func checkRoleExtends(roles map[string]*Role) error {
+	done := make(map[string]struct{}, len(roles))
+
+	var visit func(name string, path []string) error
+
+	visit = func(name string, path []string) error {
+		for _, v := range path {
+			if v == name {
+				return fmt.Errorf("roles: cycle in role inheritance: %s",
+					strings.Join(append(path, name), " -> "))
+			}
+		}
+
+		if _, ok := done[name]; ok {
+			return nil
+		}
+
+		path = append(path, name)
+
+		for _, v := range roles[name].Extends {
+			if _, ok := roles[v]; !ok {
+				return fmt.Errorf("roles: %s: extends unknown role: %s", name, v)
+			}
+			if err := visit(v, path); err != nil {
+				return err
+			}
+		}
+
+		done[name] = struct{}{}
+		return nil
+	}
+
+	for name := range roles {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// userRole returns the role to be used for the request based on the roles
+// set on the context. When the client selects an active role it's validated
+// against the roles held by the user (or the default role if none are set).
+// An empty string is returned if no role is set on the context.
+func (gj *GraphJin) userRole(c context.Context, defRole string) (string, error) {
+	var roles []string
+
+	switch v := c.Value(UserRoleKey).(type) {
+	case string:
+		if v != "" {
+			roles = []string{v}
+		}
+	case []string:
+		roles = v
+	}
+
+	// with a roles_query the roles held are only known once it's run
+	// so the active role is checked after
+	if len(roles) == 0 && gj.abacEnabled {
+		return "", nil
+	}
+
+	return gj.activeRole(c, roles, defRole)
+}
+
+// matchedRole returns the role to use from the comma separated list
+// of roles returned by the roles_query
+func (gj *GraphJin) matchedRole(c context.Context, role string) (string, error) {
+	if role == "anon" {
+		return gj.activeRole(c, []string{role}, role)
+	}
+	return gj.activeRole(c, strings.Split(role, ","), "user")
+}
+
+// hasActiveRole returns true if the client selected an active role
+func hasActiveRole(c context.Context) bool {
+	v, ok := c.Value(ActiveRoleKey).(string)
+	return ok && v != ""
+}
+
+// RequestRole returns the role a request with this context is run with, it
+// takes into account the active role selected by the client and the roles
+// held by the user. With a roles_query the role is looked up in the database.
+func (gj *GraphJin) RequestRole(c context.Context) (string, error) {
+	defRole := "anon"
+	if c.Value(UserIDKey) != nil {
+		defRole = "user"
+	}
+
+	role, err := gj.userRole(c, defRole)
+	if err != nil || role != "" {
+		return role, err
+	}
+
+	if !gj.abacEnabled || defRole == "anon" {
+		return defRole, nil
+	}
+
+	sc := &scontext{Context: c, gj: gj}
+	return sc.executeRoleQuery(gj.db)
+}
+
+// activeRole returns the role selected by the client from the list of roles
+// held by the user or the combined role of all of them
+func (gj *GraphJin) activeRole(c context.Context, roles []string, defRole string) (string, error) {
+	if v, ok := c.Value(ActiveRoleKey).(string); ok && v != "" {
+		allowed := roles
+		if len(allowed) == 0 {
+			allowed = []string{defRole}
+		}
+
+		found := false
+		for _, r := range allowed {
+			if r == v {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return "", fmt.Errorf("active role not allowed: %s", v)
+		}
+		roles = []string{v}
+	}
+
+	switch len(roles) {
+	case 0:
+		return "", nil
+	case 1:
+		return roles[0], nil
+	}
+
+	return gj.combinedRole(roles)
+}
+
+// combinedRole returns the name of the role that combines the table configs
+// of all the roles in the list. Combined roles are added on first use.
+func (gj *GraphJin) combinedRole(roles []string) (string, error) {
+	rl := make([]string, 0, len(roles))
+	rm := make(map[string]struct{}, len(roles))
+
+	for _, v := range roles {
+		if _, ok := rm[v]; ok {
+			continue
+		}
+		if _, ok := gj.roles[v]; !ok {
+			return "", fmt.Errorf(`roles '%s' not defined in c.gj.config`, v)
+		}
+		rm[v] = struct{}{}
+		rl = append(rl, v)
+	}
+
+	if len(rl) == 1 {
+		return rl[0], nil
+	}
+
+	sort.Strings(rl)
+	name := strings.Join(rl, ",")
+
+	if _, ok := gj.croles.Load(name); ok {
+		return name, nil
+	}
+
+	gj.crolesLock.Lock()
+	defer gj.crolesLock.Unlock()
+
+	if _, ok := gj.croles.Load(name); ok {
+		return name, nil
+	}
+
+	r := &Role{Name: name, tm: make(map[string]*RoleTable)}
+	r.Tables = unionRoleTables(gj.roles, rl)
+
+	for i, t := range r.Tables {
+		if err := addRole(gj.qc, *r, t, gj.conf.DefaultBlock); err != nil {
+			return "", err
+		}
+		r.tm[t.Name] = &r.Tables[i]
+	}
+
+	gj.croles.Store(name, r)
+	return name, nil
+}
+
+// getRole returns a role from the config or a combined role
+func (gj *GraphJin) getRole(name string) (*Role, bool) {
+	if r, ok := gj.roles[name]; ok {
+		return r, true
+	}
+	if v, ok := gj.croles.Load(name); ok {
+		return v.(*Role), true
+	}
+	return nil, false
+}
+
+// unionRoleTables returns the table configs for a user holding all the roles
+// in the list, it allows whatever any of the roles allow. A table without
+// a config in one of the roles gets no access from that role.
+func unionRoleTables(roles map[string]*Role, names []string) []RoleTable {
+	var tables []RoleTable
+	tm := make(map[string]int)
+
+	for _, name := range names {
+		for _, t := range resolveRoleTables(roles, *roles[name]) {
+			t = normalizeRoleTable(t)
+
+			if i, ok := tm[t.Name]; ok {
+				tables[i] = unionRoleTable(tables[i], t)
+			} else {
+				tm[t.Name] = len(tables)
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	return tables
+}
+
+// normalizeRoleTable replaces missing operation configs with explicit ones
+// so a missing config is treated as unrestricted (or blocked when read-only)
+func normalizeRoleTable(t RoleTable) RoleTable {
+	ro := t.ReadOnly
+	t.ReadOnly = false
+
+	if t.Query == nil {
+		t.Query = &Query{}
+	}
+	if t.Insert == nil {
+		t.Insert = &Insert{Block: ro}
+	}
+	if t.Update == nil {
+		t.Update = &Update{Block: ro}
+	}
+	if t.Upsert == nil {
+		t.Upsert = &Upsert{Block: ro}
+	}
+	if t.Delete == nil {
+		t.Delete = &Delete{Block: ro}
+	}
+	return t
+}
+
+// resolveRoleTables returns the table configs of a role merged with those of
+// the roles it extends. The same table inherited from multiple roles is merged
+// with filters OR'ed, columns unioned and later presets taking precedence. The
+// table configs defined on the role itself are then applied on top, its filters
+// are AND'ed with the inherited ones while all other values override them.
+func resolveRoleTables(roles map[string]*Role, r Role) []RoleTable {
+	if len(r.Extends) == 0 {
+		return r.Tables
+	}
+
+	var tables []RoleTable
+	tm := make(map[string]int)
+
+	for _, name := range r.Extends {
+		pr, ok := roles[name]
+		if !ok {
+			continue
+		}
+		for _, t := range resolveRoleTables(roles, *pr) {
+			if i, ok := tm[t.Name]; ok {
+				// a missing config is unrestricted so it's made explicit
+				// before the merge, the same as with multiple roles
+				tables[i] = unionRoleTable(normalizeRoleTable(tables[i]), normalizeRoleTable(t))
+			} else {
+				tm[t.Name] = len(tables)
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	for _, t := range r.Tables {
+		if i, ok := tm[t.Name]; ok {
+			tables[i] = extendRoleTable(tables[i], t)
+		} else {
+			tm[t.Name] = len(tables)
+			tables = append(tables, t)
+		}
+	}
+
+	return tables
+}
+
+// unionRoleTable merges the configs of the same table from two different
+// roles, the result allows whatever either of them allows.
+func unionRoleTable(a, b RoleTable) RoleTable {
+	t := RoleTable{Name: a.Name, ReadOnly: a.ReadOnly && b.ReadOnly}
+
+	switch {
+	case a.Query == nil || (b.Query != nil && a.Query.Block):
+		t.Query = b.Query
+	case b.Query == nil || b.Query.Block:
+		t.Query = a.Query
+	default:
+		t.Query = &Query{
+			Limit:            maxLimit(a.Query.Limit, b.Query.Limit),
+			Filters:          orFilters(a.Query.Filters, b.Query.Filters),
+			Columns:          unionColumns(a.Query.Columns, b.Query.Columns),
+			Masks:            unionMasks(a.Query, b.Query),
+			DisableFunctions: a.Query.DisableFunctions && b.Query.DisableFunctions,
+			WithDeleted:      a.Query.WithDeleted || b.Query.WithDeleted,
+			Block:            a.Query.Block && b.Query.Block,
+		}
+	}
+
+	switch {
+	case a.Insert == nil || (b.Insert != nil && a.Insert.Block):
+		t.Insert = b.Insert
+	case b.Insert == nil || b.Insert.Block:
+		t.Insert = a.Insert
+	default:
+		t.Insert = &Insert{
+			Filters: orFilters(a.Insert.Filters, b.Insert.Filters),
+			Columns: unionColumns(a.Insert.Columns, b.Insert.Columns),
+			Check:   orFilters(a.Insert.Check, b.Insert.Check),
+			Presets: mergePresets(a.Insert.Presets, b.Insert.Presets),
+			Block:   a.Insert.Block && b.Insert.Block,
+		}
+	}
+
+	switch {
+	case a.Update == nil || (b.Update != nil && a.Update.Block):
+		t.Update = b.Update
+	case b.Update == nil || b.Update.Block:
+		t.Update = a.Update
+	default:
+		t.Update = &Update{
+			Filters: orFilters(a.Update.Filters, b.Update.Filters),
+			Columns: unionColumns(a.Update.Columns, b.Update.Columns),
+			Check:   orFilters(a.Update.Check, b.Update.Check),
+			Presets: mergePresets(a.Update.Presets, b.Update.Presets),
+			Block:   a.Update.Block && b.Update.Block,
+		}
+	}
+
+	switch {
+	case a.Upsert == nil || (b.Upsert != nil && a.Upsert.Block):
+		t.Upsert = b.Upsert
+	case b.Upsert == nil || b.Upsert.Block:
+		t.Upsert = a.Upsert
+	default:
+		t.Upsert = &Upsert{
+			Filters: orFilters(a.Upsert.Filters, b.Upsert.Filters),
+			Columns: unionColumns(a.Upsert.Columns, b.Upsert.Columns),
+			Check:   orFilters(a.Upsert.Check, b.Upsert.Check),
+			Presets: mergePresets(a.Upsert.Presets, b.Upsert.Presets),
+			Block:   a.Upsert.Block && b.Upsert.Block,
+		}
+	}
+
+	switch {
+	case a.Delete == nil || (b.Delete != nil && a.Delete.Block):
+		t.Delete = b.Delete
+	case b.Delete == nil || b.Delete.Block:
+		t.Delete = a.Delete
+	default:
+		t.Delete = &Delete{
+			Filters: orFilters(a.Delete.Filters, b.Delete.Filters),
+			Columns: unionColumns(a.Delete.Columns, b.Delete.Columns),
+			Block:   a.Delete.Block && b.Delete.Block,
+		}
+	}
+
+	return t
+}
+
+// extendRoleTable applies the table config defined on a role (b) on top of
+// the one it inherited (a).
+func extendRoleTable(a, b RoleTable) RoleTable {
+	t := RoleTable{Name: a.Name, ReadOnly: b.ReadOnly}
+
+	t.Query = a.Query
+	if b.Query != nil {
+		t.Query = &Query{
+			Limit:            b.Query.Limit,
+			Filters:          b.Query.Filters,
+			Columns:          b.Query.Columns,
+			Masks:            b.Query.Masks,
+			DisableFunctions: b.Query.DisableFunctions,
+			WithDeleted:      b.Query.WithDeleted,
+			Block:            b.Query.Block,
+		}
+		if a.Query != nil {
+			if t.Query.Limit == 0 {
+				t.Query.Limit = a.Query.Limit
+			}
+			t.Query.Filters = andFilters(a.Query.Filters, b.Query.Filters)
+			t.Query.Masks = mergePresets(a.Query.Masks, b.Query.Masks)
+			if len(t.Query.Columns) == 0 {
+				t.Query.Columns = a.Query.Columns
+			}
+		}
+	}
+
+	t.Insert = a.Insert
+	if b.Insert != nil {
+		t.Insert = &Insert{
+			Filters: b.Insert.Filters,
+			Columns: b.Insert.Columns,
+			Check:   b.Insert.Check,
+			Presets: b.Insert.Presets,
+			Block:   b.Insert.Block,
+		}
+		if a.Insert != nil {
+			t.Insert.Filters = andFilters(a.Insert.Filters, b.Insert.Filters)
+			t.Insert.Check = andFilters(a.Insert.Check, b.Insert.Check)
+			if len(t.Insert.Columns) == 0 {
+				t.Insert.Columns = a.Insert.Columns
+			}
+			t.Insert.Presets = mergePresets(a.Insert.Presets, b.Insert.Presets)
+		}
+	}
+
+	t.Update = a.Update
+	if b.Update != nil {
+		t.Update = &Update{
+			Filters: b.Update.Filters,
+			Columns: b.Update.Columns,
+			Check:   b.Update.Check,
+			Presets: b.Update.Presets,
+			Block:   b.Update.Block,
+		}
+		if a.Update != nil {
+			t.Update.Filters = andFilters(a.Update.Filters, b.Update.Filters)
+			t.Update.Check = andFilters(a.Update.Check, b.Update.Check)
+			if len(t.Update.Columns) == 0 {
+				t.Update.Columns = a.Update.Columns
+			}
+			t.Update.Presets = mergePresets(a.Update.Presets, b.Update.Presets)
+		}
+	}
+
+	t.Upsert = a.Upsert
+	if b.Upsert != nil {
+		t.Upsert = &Upsert{
+			Filters: b.Upsert.Filters,
+			Columns: b.Upsert.Columns,
+			Check:   b.Upsert.Check,
+			Presets: b.Upsert.Presets,
+			Block:   b.Upsert.Block,
+		}
+		if a.Upsert != nil {
+			t.Upsert.Filters = andFilters(a.Upsert.Filters, b.Upsert.Filters)
+			t.Upsert.Check = andFilters(a.Upsert.Check, b.Upsert.Check)
+			if len(t.Upsert.Columns) == 0 {
+				t.Upsert.Columns = a.Upsert.Columns
+			}
+			t.Upsert.Presets = mergePresets(a.Upsert.Presets, b.Upsert.Presets)
+		}
+	}
+
+	t.Delete = a.Delete
+	if b.Delete != nil {
+		t.Delete = &Delete{
+			Filters: b.Delete.Filters,
+			Columns: b.Delete.Columns,
+			Block:   b.Delete.Block,
+		}
+		if a.Delete != nil {
+			t.Delete.Filters = andFilters(a.Delete.Filters, b.Delete.Filters)
+			if len(t.Delete.Columns) == 0 {
+				t.Delete.Columns = a.Delete.Columns
+			}
+		}
+	}
+
+	return t
+}
+
+// andFilters combines two filter lists, all filters in a list are AND'ed
+func andFilters(a, b []string) []string {
+	f := make([]string, 0, len(a)+len(b))
+	f = append(f, a...)
+	return append(f, b...)
+}
+
+// orFilters combines two filter lists into a single OR filter. An empty
+// list allows all rows and the "false" filter none.
+func orFilters(a, b []string) []string {
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		return nil
+	case isFalseFilter(a):
+		return b
+	case isFalseFilter(b):
+		return a
+	}
+	return []string{fmt.Sprintf("{ or: [ %s, %s ] }", filterExp(a), filterExp(b))}
+}
+
+func isFalseFilter(f []string) bool {
+	for _, v := range f {
+		if v == "false" {
+			return true
+		}
+	}
+	return false
+}
+
+func filterExp(f []string) string {
+	if len(f) == 1 {
+		return f[0]
+	}
+	return fmt.Sprintf("{ and: [ %s ] }", strings.Join(f, ", "))
+}
+
+// unionColumns combines two column lists, an empty list allows all columns
+func unionColumns(a, b []string) []string {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+
+	cols := make([]string, 0, len(a)+len(b))
+	cm := make(map[string]struct{}, len(a)+len(b))
+
+	for _, list := range [][]string{a, b} {
+		for _, c := range list {
+			k := strings.ToLower(c)
+			if _, ok := cm[k]; ok {
+				continue
+			}
+			cm[k] = struct{}{}
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// unionMasks keeps a column masked only if it's masked or not
+// allowed at all in the other query config
+func unionMasks(a, b *Query) map[string]string {
+	m := make(map[string]string)
+
+	for _, v := range [][2]*Query{{a, b}, {b, a}} {
+		for k, mt := range v[0].Masks {
+			if _, ok := m[k]; ok {
+				continue
+			}
+			if _, ok := v[1].Masks[k]; ok || !columnInList(v[1].Columns, k) {
+				m[k] = mt
+			}
+		}
+	}
+	return m
+}
+
+func columnInList(cols []string, name string) bool {
+	if len(cols) == 0 {
+		return true
+	}
+	for _, c := range cols {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePresets combines two preset maps, values in b take precedence
+func mergePresets(a, b map[string]string) map[string]string {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+
+	p := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		p[k] = v
+	}
+	for k, v := range b {
+		p[k] = v
+	}
+	return p
+}
+
+// maxLimit returns the larger of two limits, zero means no limit
+func maxLimit(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
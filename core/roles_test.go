@@ -0,0 +1,198 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dosco/graphjin/core/internal/psql"
+	"github.com/dosco/graphjin/core/internal/qcode"
+	"github.com/dosco/graphjin/core/internal/sdata"
+)
+
+func TestUnionRoleTables(t *testing.T) {
+	roles := map[string]*Role{
+		"editor": {Name: "editor", Tables: []RoleTable{
+			{Name: "posts", Query: &Query{Columns: []string{"id", "title"}, Limit: 10}},
+		}},
+		"billing": {Name: "billing", Tables: []RoleTable{
+			{Name: "posts", Query: &Query{Columns: []string{"id", "body"}, Limit: 20}},
+			{Name: "invoices", Query: &Query{Filters: []string{"{ org_id: $org_id }"}}},
+		}},
+	}
+
+	tables := unionRoleTables(roles, []string{"editor", "billing"})
+
+	tm := make(map[string]RoleTable)
+	for _, t := range tables {
+		tm[t.Name] = t
+	}
+
+	if len(tm) != 2 {
+		t.Fatalf("expected 2 tables got %d", len(tm))
+	}
+
+	posts := tm["posts"]
+	if posts.Query.Limit != 20 {
+		t.Errorf("expected limit 20 got %d", posts.Query.Limit)
+	}
+	if len(posts.Query.Columns) != 3 {
+		t.Errorf("expected columns id, title and body got %v", posts.Query.Columns)
+	}
+
+	// a table granted by only one of the roles is kept as is
+	inv, ok := tm["invoices"]
+	if !ok {
+		t.Fatal("expected table invoices to be kept")
+	}
+	if len(inv.Query.Filters) != 1 || inv.Query.Filters[0] != "{ org_id: $org_id }" {
+		t.Errorf("unexpected filters %v", inv.Query.Filters)
+	}
+}
+
+func TestResolveRoleTablesUnrestricted(t *testing.T) {
+	roles := map[string]*Role{
+		"reader": {Name: "reader", Tables: []RoleTable{
+			{Name: "posts", Insert: &Insert{Block: true}},
+		}},
+		"editor": {Name: "editor", Tables: []RoleTable{
+			{Name: "posts", Query: &Query{Columns: []string{"id", "title"}}},
+		}},
+		"manager": {Name: "manager", Extends: []string{"reader", "editor"}},
+	}
+
+	tables := resolveRoleTables(roles, *roles["manager"])
+
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table got %d", len(tables))
+	}
+
+	// the reader has no query config so it can read every column
+	posts := tables[0]
+	if posts.Query == nil || len(posts.Query.Columns) != 0 || posts.Query.Block {
+		t.Errorf("expected unrestricted query got %+v", posts.Query)
+	}
+
+	// the editor has no insert config so it can insert
+	if posts.Insert == nil || posts.Insert.Block {
+		t.Errorf("expected inserts allowed got %+v", posts.Insert)
+	}
+}
+
+func newABACTestGJ(t *testing.T) *GraphJin {
+	sc, err := sdata.GetTestSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qc, err := qcode.NewCompiler(sc, qcode.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &Config{
+		RolesQuery: `SELECT * FROM users WHERE id = $user_id`,
+		Roles: []Role{
+			{Name: "anon"},
+			{Name: "user"},
+			{Name: "admin", Match: "id = 1000"},
+			{Name: "editor", Match: "id = 2000"},
+		},
+	}
+
+	gj := &GraphJin{conf: conf, qc: qc, abacEnabled: true, roles: make(map[string]*Role)}
+
+	for i, r := range conf.Roles {
+		r.Tables = []RoleTable{{Name: "products", Query: &Query{Limit: 10}}}
+		conf.Roles[i] = r
+		gj.roles[r.Name] = &conf.Roles[i]
+	}
+	return gj
+}
+
+func TestActiveRoleWithRolesQuery(t *testing.T) {
+	gj := newABACTestGJ(t)
+
+	// the active role is only checked once the roles_query has run
+	c := context.WithValue(context.Background(), ActiveRoleKey, "editor")
+
+	role, err := gj.userRole(c, "user")
+	if err != nil || role != "" {
+		t.Fatalf("expected the role to be found by the roles_query got '%s' (%v)", role, err)
+	}
+
+	tests := []struct {
+		matched string
+		active  string
+		exp     string
+		err     bool
+	}{
+		{"admin,editor", "editor", "editor", false},
+		{"admin,editor", "", "admin,editor", false},
+		{"admin,editor", "user", "", true},
+		{"editor", "admin", "", true},
+		{"user", "user", "user", false},
+		{"user", "", "user", false},
+		{"anon", "anon", "anon", false},
+		{"anon", "admin", "", true},
+	}
+
+	for _, v := range tests {
+		c := context.Background()
+		if v.active != "" {
+			c = context.WithValue(c, ActiveRoleKey, v.active)
+		}
+
+		role, err := gj.matchedRole(c, v.matched)
+		if v.err {
+			if err == nil {
+				t.Errorf("%s (%s): expected an error got '%s'", v.matched, v.active, role)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s (%s): %s", v.matched, v.active, err)
+			continue
+		}
+		if role != v.exp {
+			t.Errorf("%s (%s): expected '%s' got '%s'", v.matched, v.active, v.exp, role)
+		}
+	}
+}
+
+// queries and mutations find the roles of the user the same way
+func TestRoleMatchQueries(t *testing.T) {
+	gj := newABACTestGJ(t)
+
+	if err := gj.prepareRoleStmt(); err != nil {
+		t.Fatal(err)
+	}
+
+	var w bytes.Buffer
+	gj.renderRoleMatch(&w, &psql.Metadata{})
+	match := w.String()
+
+	if !strings.Contains(match, `CONCAT_WS(','`) {
+		t.Fatalf("expected all the roles matched got %s", match)
+	}
+
+	if !strings.Contains(gj.roleStmt, match) {
+		t.Errorf("expected the roles statement to use %s got %s", match, gj.roleStmt)
+	}
+
+	stmts := []stmt{
+		{role: gj.roles["user"], sql: `SELECT 1`},
+		{role: gj.roles["admin"], sql: `SELECT 2`},
+		{role: gj.roles["editor"], sql: `SELECT 3`},
+	}
+
+	q, err := gj.renderUserQuery(&psql.Metadata{}, stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(q, match) {
+		t.Errorf("expected the user query to use %s got %s", match, q)
+	}
+}
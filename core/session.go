@@ -0,0 +1,157 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sessionTx returns true if requests have to be run in a transaction
+// to set the database role or local settings
+func (gj *GraphJin) sessionTx() bool {
+	return gj.conf.SetUserID || gj.conf.SetLocalRole || len(gj.conf.LocalSettings) != 0
+}
+
+// setSession sets the local settings and database role for the
+// transaction. With a roles query the role of a query is only known
+// once it's run so the role used is either 'user' or 'anon'.
+func (c *scontext) setSession(tx *sql.Tx, role string) error {
+	if err := c.setLocalSettings(tx, role); err != nil {
+		return err
+	}
+
+	if c.gj.conf.SetLocalRole {
+		return c.setLocalRole(tx, role)
+	}
+	return nil
+}
+
+// setLocalSettings sets the user id and the local settings for the
+// duration of the transaction using parameterized calls to set_config
+func (c *scontext) setLocalSettings(tx *sql.Tx, role string) error {
+	q, vals, err := c.localSettings(role)
+	if err != nil || q == "" {
+		return err
+	}
+
+	_, err = tx.ExecContext(c, q, vals...)
+	return err
+}
+
+// localSettings returns the query and values to set the local settings
+func (c *scontext) localSettings(role string) (string, []interface{}, error) {
+	var names []string
+
+	for k := range c.gj.conf.LocalSettings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	vals := make([]interface{}, 0, (len(names)+1)*2)
+
+	if c.gj.conf.SetUserID {
+		if v := c.Value(UserIDKey); v != nil {
+			vals = append(vals, "user.id", fmt.Sprint(v))
+		}
+	}
+
+	for _, k := range names {
+		v, err := c.settingValue(c.gj.conf.LocalSettings[k], role)
+		if err != nil {
+			return "", nil, fmt.Errorf("local_settings: %s: %w", k, err)
+		}
+		vals = append(vals, k, v)
+	}
+
+	if len(vals) == 0 {
+		return "", nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`SELECT `)
+
+	for i := 0; i < len(vals); i += 2 {
+		if i != 0 {
+			sb.WriteString(`, `)
+		}
+		fmt.Fprintf(&sb, `set_config($%d, $%d, true)`, i+1, i+2)
+	}
+
+	return sb.String(), vals, nil
+}
+
+// setLocalRole switches to the database role matching the user
+// role till the end of the transaction
+func (c *scontext) setLocalRole(tx *sql.Tx, role string) error {
+	if role == "" {
+		return nil
+	}
+
+	if strings.ContainsRune(role, ',') {
+		return fmt.Errorf("set_local_role: combined roles not supported: %s", role)
+	}
+
+	_, err := tx.ExecContext(c, `SET LOCAL ROLE "`+strings.ReplaceAll(role, `"`, `""`)+`"`)
+	return err
+}
+
+// settingValue resolves the value of a local setting, values starting
+// with a `$` are variables taken from the request and are empty if not set
+func (c *scontext) settingValue(val, role string) (string, error) {
+	if !strings.HasPrefix(val, "$") {
+		return val, nil
+	}
+	name := val[1:]
+
+	switch name {
+	case "user_id":
+		return valueString(c.Value(UserIDKey))
+
+	case "user_id_provider":
+		return valueString(c.Value(UserIDProviderKey))
+
+	case "user_role":
+		return role, nil
+
+	case "jwt_claims":
+		if v := c.Value(UserClaimsKey); v != nil {
+			b, err := json.Marshal(v)
+			return string(b), err
+		}
+		return "", nil
+	}
+
+	if strings.HasPrefix(name, "jwt.") {
+		if v, ok := c.Value(UserClaimsKey).(map[string]interface{}); ok {
+			return valueString(v[name[4:]])
+		}
+		return "", nil
+	}
+
+	if c.rc != nil {
+		switch v := c.rc.Vars[name].(type) {
+		case func() string:
+			return v(), nil
+		case string:
+			return v, nil
+		}
+	}
+
+	return "", nil
+}
+
+func valueString(v interface{}) (string, error) {
+	switch v1 := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v1, nil
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v1)
+		return string(b), err
+	default:
+		return fmt.Sprint(v1), nil
+	}
+}
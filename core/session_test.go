@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dosco/graphjin/core/internal/allow"
+)
+
+func TestSettingValue(t *testing.T) {
+	ctx := context.WithValue(context.Background(), UserIDKey, 5)
+	ctx = context.WithValue(ctx, UserClaimsKey, map[string]interface{}{
+		"org": "acme", "groups": []interface{}{"a", "b"},
+	})
+
+	c := &scontext{
+		Context: ctx,
+		gj:      &GraphJin{conf: &Config{}},
+		rc: &ReqConfig{Vars: map[string]interface{}{
+			"plan": func() string { return "pro" },
+		}},
+	}
+
+	tests := []struct {
+		val, exp string
+	}{
+		{"static", "static"},
+		{"$user_id", "5"},
+		{"$user_id_provider", ""},
+		{"$user_role", "admin"},
+		{"$jwt.org", "acme"},
+		{"$jwt.groups", `["a","b"]`},
+		{"$jwt.missing", ""},
+		{"$plan", "pro"},
+		{"$unknown", ""},
+	}
+
+	for _, v := range tests {
+		s, err := c.settingValue(v.val, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != v.exp {
+			t.Errorf("%s: expected '%s' got '%s'", v.val, v.exp, s)
+		}
+	}
+}
+
+func TestLocalSettings(t *testing.T) {
+	ctx := context.WithValue(context.Background(), UserIDKey, 5)
+
+	c := &scontext{
+		Context: ctx,
+		gj: &GraphJin{conf: &Config{
+			SetUserID: true,
+			LocalSettings: map[string]string{
+				"request.role":   "$user_role",
+				"request.org_id": "10",
+			},
+		}},
+	}
+
+	q, vals, err := c.localSettings("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expQ := `SELECT set_config($1, $2, true), set_config($3, $4, true), set_config($5, $6, true)`
+	if q != expQ {
+		t.Errorf("expected query %s got %s", expQ, q)
+	}
+
+	// settings are sorted by name after the user id
+	expV := []interface{}{"user.id", "5", "request.org_id", "10", "request.role", "user"}
+	if !reflect.DeepEqual(vals, expV) {
+		t.Errorf("expected values %v got %v", expV, vals)
+	}
+
+	c.gj.conf = &Config{}
+
+	if q, _, _ := c.localSettings("user"); q != "" {
+		t.Errorf("expected no query without settings got %s", q)
+	}
+}
+
+func TestSubscribeSessionSettings(t *testing.T) {
+	query := `subscription { products { id } }`
+
+	tests := []struct {
+		conf Config
+		err  string
+	}{
+		// set_user_id is not rejected so the subscription goes on
+		// to fail on the missing query name
+		{Config{SetUserID: true, EnforceAllowList: true}, "query name is required"},
+		{Config{SetLocalRole: true}, "not supported with set_local_role"},
+		{Config{LocalSettings: map[string]string{"request.org_id": "$user_id"}}, "not supported with set_local_role"},
+	}
+
+	for _, v := range tests {
+		v := v
+		gj := &GraphJin{conf: &v.conf, allowList: &allow.List{}}
+
+		_, err := gj.Subscribe(context.Background(), query, nil)
+		if err == nil || !strings.Contains(err.Error(), v.err) {
+			t.Errorf("expected error '%s' got '%v'", v.err, err)
+		}
+	}
+}
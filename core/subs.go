@@ -84,6 +84,12 @@ func (gj *GraphJin) SubscribeEx(
 		return nil, errors.New("subscription: not a subscription query")
 	}
 
+	// polls batch the queries of many users together so the database
+	// role and local settings can't be set for each of them
+	if gj.conf.SetLocalRole || len(gj.conf.LocalSettings) != 0 {
+		return nil, errors.New("subscription: not supported with set_local_role or local_settings")
+	}
+
 	if name == "" {
 		if gj.allowList != nil && gj.conf.EnforceAllowList {
 			return nil, errors.New("subscription: query name is required")
@@ -329,6 +335,16 @@ func (gj *GraphJin) checkUpdates(s *sub, mv mval, start int) {
 			return
 		}
 
+		if cur.data, err = gj.decryptColumns(s.q.st.qc, cur.data); err != nil {
+			gj.log.Printf("ERR %s", err)
+			return
+		}
+
+		if cur.data, err = gj.encodeGlobalIDs(s.q.st.qc, cur.data); err != nil {
+			gj.log.Printf("ERR %s", err)
+			return
+		}
+
 		// we're expecting a cursor but the cursor was null
 		// so we skip this one.
 		if s.cindx != -1 && cur.value == "" {
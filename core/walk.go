@@ -0,0 +1,189 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/dosco/graphjin/core/internal/qcode"
+)
+
+// colValFn returns the new value of a column in the result
+type colValFn func(sel *qcode.Select, col qcode.Column, v json.RawMessage) (json.RawMessage, error)
+
+// mapColumns calls fn with the value of every column in the result for
+// which match returns true. The values are found by following the path of
+// the selections so fields with the same name elsewhere in the result,
+// for example inside a json column, are left as is.
+func mapColumns(
+	qc *qcode.QCode,
+	data []byte,
+	match func(col qcode.Column) bool,
+	fn colValFn) ([]byte, error) {
+
+	if len(data) == 0 || data[0] != '{' {
+		return data, nil
+	}
+
+	return mapObject(data, func(k string, v json.RawMessage) (json.RawMessage, error) {
+		for _, id := range qc.Roots {
+			sel := &qc.Selects[id]
+			if sel.FieldName == k {
+				return mapSelect(qc, sel, v, match, fn)
+			}
+		}
+		return v, nil
+	})
+}
+
+// mapSelect walks the value returned for a selection, a single row
+// or a list of rows or the connection wrapping them
+func mapSelect(
+	qc *qcode.QCode,
+	sel *qcode.Select,
+	v json.RawMessage,
+	match func(col qcode.Column) bool,
+	fn colValFn) (json.RawMessage, error) {
+
+	switch {
+	case len(v) == 0:
+		return v, nil
+
+	case v[0] == '[':
+		return mapList(v, func(v json.RawMessage) (json.RawMessage, error) {
+			return mapSelect(qc, sel, v, match, fn)
+		})
+
+	case v[0] != '{':
+		return v, nil
+
+	case sel.Paging.Connection:
+		return mapConn(qc, sel, sel.Conn, v, match, fn)
+	}
+
+	return mapObject(v, func(k string, v json.RawMessage) (json.RawMessage, error) {
+		for _, col := range sel.Cols {
+			if col.FieldName == k && !col.Base && match(col) {
+				return fn(sel, col, v)
+			}
+		}
+		for _, id := range sel.Children {
+			csel := &qc.Selects[id]
+			if csel.FieldName == k {
+				return mapSelect(qc, csel, v, match, fn)
+			}
+		}
+		return v, nil
+	})
+}
+
+// mapConn walks the edges of a connection to the rows in its nodes
+func mapConn(
+	qc *qcode.QCode,
+	sel *qcode.Select,
+	fields []qcode.ConnField,
+	v json.RawMessage,
+	match func(col qcode.Column) bool,
+	fn colValFn) (json.RawMessage, error) {
+
+	switch {
+	case len(v) == 0:
+		return v, nil
+
+	case v[0] == '[':
+		return mapList(v, func(v json.RawMessage) (json.RawMessage, error) {
+			return mapConn(qc, sel, fields, v, match, fn)
+		})
+
+	case v[0] != '{':
+		return v, nil
+	}
+
+	return mapObject(v, func(k string, v json.RawMessage) (json.RawMessage, error) {
+		for _, f := range fields {
+			if f.FieldName != k {
+				continue
+			}
+			switch f.Name {
+			case "edges":
+				return mapConn(qc, sel, f.Fields, v, match, fn)
+			case "node":
+				s := *sel
+				s.Paging.Connection = false
+				return mapSelect(qc, &s, v, match, fn)
+			}
+		}
+		return v, nil
+	})
+}
+
+// mapList calls fn with each value in a json list
+func mapList(v json.RawMessage, fn func(v json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	var list []json.RawMessage
+
+	if err := json.Unmarshal(v, &list); err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		v1, err := fn(list[i])
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v1
+	}
+	return json.Marshal(list)
+}
+
+// mapObject calls fn with each key and value in a json object, unlike
+// decoding into a map the order of the keys is kept.
+func mapObject(v json.RawMessage, fn func(k string, v json.RawMessage) (json.RawMessage, error)) (json.RawMessage, error) {
+	var b bytes.Buffer
+
+	d := json.NewDecoder(bytes.NewReader(v))
+
+	if t, err := d.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New("expecting a json object")
+	}
+
+	b.WriteByte('{')
+
+	for i := 0; d.More(); i++ {
+		var val json.RawMessage
+
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		k, ok := t.(string)
+		if !ok {
+			return nil, errors.New("expecting a json object key")
+		}
+
+		if err := d.Decode(&val); err != nil {
+			return nil, err
+		}
+
+		if val, err = fn(k, val); err != nil {
+			return nil, err
+		}
+
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -50,6 +50,20 @@ type Serv struct {
 	APIPath        string   `mapstructure:"api_path"`
 	CacheControl   string   `mapstructure:"cache_control"`
 
+	// HTTP server timeouts
+	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+
+	// TLS struct contains config values for serving over HTTPS and
+	// verifying client certificates
+	TLS struct {
+		CertFile     string `mapstructure:"cert_file"`
+		KeyFile      string `mapstructure:"key_file"`
+		ClientCAFile string `mapstructure:"client_ca_file"`
+		ClientAuth   string `mapstructure:"client_auth"`
+	}
+
 	// Telemetry struct contains OpenCensus metrics and tracing related config
 	Telemetry struct {
 		Debug    bool
@@ -95,12 +109,27 @@ type Serv struct {
 	Actions []Action
 
 	RateLimiter struct {
-		Rate     float64
-		Bucket   int
-		IPHeader string `mapstructure:"ip_header"`
+		Rate           float64
+		Bucket         int
+		IPHeader       string `mapstructure:"ip_header"`
+		Key            string
+		APIKeyHeader   string        `mapstructure:"api_key_header"`
+		APIKeys        []string      `mapstructure:"api_keys"`
+		TrustedProxies []string      `mapstructure:"trusted_proxies"`
+		IdleTimeout    time.Duration `mapstructure:"idle_timeout"`
+		MaxKeys        int           `mapstructure:"max_keys"`
+		Roles          []RoleRateLimit
 	} `mapstructure:"rate_limiter"`
 }
 
+// RoleRateLimit struct contains the rate limit for requests made
+// with a specific role
+type RoleRateLimit struct {
+	Name   string
+	Rate   float64
+	Bucket int
+}
+
 // Auth struct contains authentication related config values used by the GraphJin service
 type Auth struct {
 	Name          string
@@ -130,6 +130,16 @@ e.g. db:migrate -+1
 		Run:   cmdNew(servConf),
 	})
 
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "schema:dump [ROLE] [FILE]",
+		Short: "Dump the GraphQL schema",
+		Long: `Print the GraphQL schema (SDL) seen by a role or save it to a file.
+
+The role defaults to 'user'.
+e.g. schema:dump anon ./schema.graphql`,
+		Run: cmdSchemaDump(servConf),
+	})
+
 	// rootCmd.AddCommand(&cobra.Command{
 	// 	Use:   fmt.Sprintf("conf:dump [%s]", strings.Join(viper.SupportedExts, "|")),
 	// 	Short: "Dump config to file",
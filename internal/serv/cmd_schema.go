@@ -0,0 +1,78 @@
+package serv
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dosco/graphjin/core"
+	"github.com/spf13/cobra"
+)
+
+func cmdSchemaDump(servConf *ServConfig) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		var err error
+
+		if len(args) > 2 {
+			cmd.Help() //nolint: errcheck
+			os.Exit(1)
+		}
+
+		role := "user"
+		if len(args) != 0 {
+			role = args[0]
+		}
+
+		if servConf.conf, err = initConf(servConf); err != nil {
+			servConf.log.Fatalf("ERR failed to read config: %s", err)
+		}
+
+		servConf.db, err = initDB(servConf, true, false)
+		if err != nil {
+			servConf.log.Fatalf("ERR failed to connect to database: %s", err)
+		}
+
+		gj, err = core.NewGraphJin(&servConf.conf.Core, servConf.db)
+		if err != nil {
+			servConf.log.Fatalf("ERR failed to initialize: %s", err)
+		}
+
+		var file string
+		if len(args) == 2 {
+			file = args[1]
+		}
+
+		if err := dumpSchema(gj, role, file, os.Stdout); err != nil {
+			servConf.log.Fatalf("ERR %s", err)
+		}
+
+		if file != "" {
+			servConf.log.Printf("INF schema for role '%s' saved to %s", role, file)
+		}
+	}
+}
+
+// schemaSource returns the GraphQL schema seen by a role
+type schemaSource interface {
+	SchemaSDL(role string) (string, error)
+}
+
+// dumpSchema writes the schema seen by the role to the file when
+// one is given or else to w
+func dumpSchema(ss schemaSource, role, file string, w io.Writer) error {
+	sdl, err := ss.SchemaSDL(role)
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	if file == "" {
+		_, err = io.WriteString(w, sdl)
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, []byte(sdl), 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package serv
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testSchema map[string]string
+
+func (ts testSchema) SchemaSDL(role string) (string, error) {
+	if v, ok := ts[role]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("role not defined: %s", role)
+}
+
+func TestDumpSchema(t *testing.T) {
+	ts := testSchema{
+		"anon": "type Query {\n  products:[productOutput]\n}\n",
+		"user": "type Query {\n  users:[userOutput]\n}\n",
+	}
+
+	dir, err := ioutil.TempDir("", "graphjin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "schema.graphql")
+	var w bytes.Buffer
+
+	if err := dumpSchema(ts, "anon", file, &w); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != ts["anon"] {
+		t.Errorf("expected the anon schema in the file got %s", b)
+	}
+
+	if w.Len() != 0 {
+		t.Errorf("expected nothing written to stdout got %s", w.String())
+	}
+
+	// without a file the schema is written out
+	if err := dumpSchema(ts, "user", "", &w); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.String() != ts["user"] {
+		t.Errorf("expected the user schema got %s", w.String())
+	}
+
+	if err := dumpSchema(ts, "admin", file, &w); err == nil {
+		t.Error("expected an error for a role that's not defined")
+	}
+
+	// the file is left as is on errors
+	if b, _ := ioutil.ReadFile(file); string(b) != ts["anon"] {
+		t.Errorf("expected the file to be unchanged got %s", b)
+	}
+}
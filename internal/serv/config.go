@@ -125,6 +125,10 @@ func (c *Config) relPath(p string) string {
 	return path.Join(c.cpath, p)
 }
 
+func (c *Config) tlsEnabled() bool {
+	return c.TLS.CertFile != "" && c.TLS.KeyFile != ""
+}
+
 func (c *Config) rateLimiterEnable() bool {
 	return c.RateLimiter.Rate > 0 && c.RateLimiter.Bucket > 0
 }
@@ -1,6 +1,7 @@
 package serv
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -38,11 +39,27 @@ type errorResp struct {
 }
 
 func apiV1Handler(servConf *ServConfig) http.Handler {
-	h, err := auth.WithAuth(http.HandlerFunc(apiV1(servConf)), &servConf.conf.Auth)
+	var h http.Handler = http.HandlerFunc(apiV1(servConf))
+
+	// API rate limiter, requests are limited by client ip before auth
+	// so the auth backends are limited too and then after auth so they
+	// can be limited by user or role
+	var rl *rateLimit
+
+	if servConf.conf.rateLimiterEnable() {
+		rl = newRateLimit(servConf)
+		h = rl.limiter(h)
+	}
+
+	h, err := auth.WithAuth(h, &servConf.conf.Auth, servConf.db)
 	if err != nil {
 		servConf.log.Fatalf("ERR %s", err)
 	}
 
+	if rl != nil {
+		h = rl.clientLimiter(h)
+	}
+
 	if len(servConf.conf.AllowedOrigins) != 0 {
 		c := cors.New(cors.Options{
 			AllowedOrigins:   servConf.conf.AllowedOrigins,
@@ -85,19 +102,11 @@ func apiV1(servConf *ServConfig) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		rc := core.ReqConfig{Vars: make(map[string]interface{})}
-
-		for k, v := range servConf.conf.HeaderVars {
-			rc.Vars[k] = func() string {
-				if v1, ok := r.Header[v]; ok {
-					return v1[0]
-				}
-				return ""
-			}
-		}
+		rc := reqConfig(servConf, ct, r)
 
 		doLog := true
 		res, err := gj.GraphQLEx(ct, req.Query, req.Vars, &rc)
+		chargeRateLimit(ct, w, res.Cost())
 
 		if !servConf.conf.Production && res.QueryName() == introspectionQuery {
 			doLog = false
@@ -142,6 +151,29 @@ func apiV1(servConf *ServConfig) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// reqConfig returns the request config with the header variables
+// and the variables set by the auth webhook
+func reqConfig(servConf *ServConfig, ct context.Context, r *http.Request) core.ReqConfig {
+	rc := core.ReqConfig{Vars: make(map[string]interface{})}
+
+	for k, v := range servConf.conf.HeaderVars {
+		v := v
+		rc.Vars[k] = func() string {
+			if v1, ok := r.Header[v]; ok {
+				return v1[0]
+			}
+			return ""
+		}
+	}
+
+	for k, v := range auth.Vars(ct) {
+		v := v
+		rc.Vars[k] = func() string { return v }
+	}
+
+	return rc
+}
+
 func reqLog(servConf *ServConfig, res *core.Result, err error) {
 	var msg string
 
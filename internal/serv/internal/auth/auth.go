@@ -2,12 +2,19 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/dosco/graphjin/core"
 )
 
+const (
+	activeRoleHeader = "X-Active-Role"
+)
+
 // Auth struct contains authentication related config values used by the GraphJin service
 type Auth struct {
 	Name          string
@@ -25,6 +32,7 @@ type Auth struct {
 		Salt          string
 		SignSalt      string `mapstructure:"sign_salt"`
 		AuthSalt      string `mapstructure:"auth_salt"`
+		SessionTable  string `mapstructure:"session_table"`
 	}
 
 	JWT struct {
@@ -40,6 +48,18 @@ type Auth struct {
 		Value  string
 		Exists bool
 	}
+
+	Webhook struct {
+		URL     string
+		TTL     time.Duration
+		Timeout time.Duration
+		MaxKeys int `mapstructure:"max_keys"`
+	}
+
+	MTLS struct {
+		UserID string `mapstructure:"user_id"`
+		Role   string
+	}
 }
 
 func SimpleHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
@@ -57,7 +77,9 @@ func SimpleHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
 		}
 
 		userRole := r.Header.Get("X-User-Role")
-		if userRole != "" {
+		if strings.Contains(userRole, ",") {
+			ctx = context.WithValue(ctx, core.UserRoleKey, splitRoles(userRole))
+		} else if userRole != "" {
 			ctx = context.WithValue(ctx, core.UserRoleKey, userRole)
 		}
 
@@ -97,9 +119,26 @@ func HeaderHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
 	}, nil
 }
 
-func WithAuth(next http.Handler, ac *Auth) (http.Handler, error) {
+// ActiveRoleHandler sets the role the client wants to use for the request,
+// it's validated against the roles held by the user.
+func ActiveRoleHandler(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := strings.TrimSpace(r.Header.Get(activeRoleHeader))
+		if role == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), core.ActiveRoleKey, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func WithAuth(next http.Handler, ac *Auth, db *sql.DB) (http.Handler, error) {
 	var err error
 
+	next = ActiveRoleHandler(next)
+
 	if ac.CredsInHeader {
 		next, err = SimpleHandler(ac, next)
 	}
@@ -110,7 +149,7 @@ func WithAuth(next http.Handler, ac *Auth) (http.Handler, error) {
 
 	switch ac.Type {
 	case "rails":
-		return RailsHandler(ac, next)
+		return RailsHandler(ac, db, next)
 
 	case "jwt":
 		return JwtHandler(ac, next)
@@ -118,6 +157,12 @@ func WithAuth(next http.Handler, ac *Auth) (http.Handler, error) {
 	case "header":
 		return HeaderHandler(ac, next)
 
+	case "webhook":
+		return WebhookHandler(ac, next)
+
+	case "mtls":
+		return MTLSHandler(ac, next)
+
 	}
 
 	return next, nil
@@ -126,3 +171,14 @@ func WithAuth(next http.Handler, ac *Auth) (http.Handler, error) {
 func IsAuth(ct context.Context) bool {
 	return ct.Value(core.UserIDKey) != nil
 }
+
+func splitRoles(value string) []string {
+	var roles []string
+
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			roles = append(roles, v)
+		}
+	}
+	return roles
+}
@@ -131,6 +131,10 @@ func JwtHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
 				ctx = context.WithValue(ctx, core.UserIDKey, claims.Subject)
 			}
 
+			if c := tokenClaims(token); c != nil {
+				ctx = context.WithValue(ctx, core.UserClaimsKey, c)
+			}
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
@@ -139,6 +143,26 @@ func JwtHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
 	}, nil
 }
 
+// tokenClaims returns all the claims in the token payload
+func tokenClaims(token *jwt.Token) map[string]interface{} {
+	var claims map[string]interface{}
+
+	parts := strings.Split(token.Raw, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	b, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
 type firebaseKeyError struct {
 	Err     error
 	Message string
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/dosco/graphjin/core"
+)
+
+func TestJwtHandlerClaims(t *testing.T) {
+	ac := &Auth{Type: "jwt"}
+	ac.JWT.Secret = "not_a_secret"
+
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    "5",
+		"org_id": 10,
+	}).SignedString([]byte(ac.JWT.Secret))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var userID, claims interface{}
+
+	h, err := JwtHandler(ac, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = r.Context().Value(core.UserIDKey)
+		claims = r.Context().Value(core.UserClaimsKey)
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if userID != "5" {
+		t.Errorf("expected user id 5 got %v", userID)
+	}
+
+	c, ok := claims.(map[string]interface{})
+	if !ok || c["org_id"] != float64(10) {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/dosco/graphjin/core"
+)
+
+// MTLSHandler authenticates requests using a verified client certificate,
+// the user ID and role are taken from the certificate subject fields.
+func MTLSHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
+	userID := ac.MTLS.UserID
+	if userID == "" {
+		userID = "cn"
+	}
+
+	if err := validCertField(userID); err != nil {
+		return nil, fmt.Errorf("auth '%s': mtls.user_id: %w", ac.Name, err)
+	}
+
+	role := ac.MTLS.Role
+	if role != "" {
+		if err := validCertField(role); err != nil {
+			return nil, fmt.Errorf("auth '%s': mtls.role: %w", ac.Name, err)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// only certificates verified against the client ca are trusted
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 ||
+			len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+
+		id := certField(cert, userID)
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		ctx = context.WithValue(ctx, core.UserIDKey, id)
+
+		if role != "" {
+			if v := certField(cert, role); v != "" {
+				ctx = context.WithValue(ctx, core.UserRoleKey, v)
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}, nil
+}
+
+func validCertField(name string) error {
+	switch name {
+	case "cn", "ou", "san", "san_email", "san_dns", "san_uri":
+		return nil
+	}
+	return fmt.Errorf("invalid certificate field: %s", name)
+}
+
+// certField returns the value of a certificate subject field. For 'san' the
+// first uri, email or dns name found is returned in that order.
+func certField(cert *x509.Certificate, name string) string {
+	switch name {
+	case "cn":
+		return cert.Subject.CommonName
+
+	case "ou":
+		if len(cert.Subject.OrganizationalUnit) != 0 {
+			return cert.Subject.OrganizationalUnit[0]
+		}
+
+	case "san":
+		if v := certField(cert, "san_uri"); v != "" {
+			return v
+		}
+		if v := certField(cert, "san_email"); v != "" {
+			return v
+		}
+		return certField(cert, "san_dns")
+
+	case "san_uri":
+		if len(cert.URIs) != 0 {
+			return cert.URIs[0].String()
+		}
+
+	case "san_email":
+		if len(cert.EmailAddresses) != 0 {
+			return cert.EmailAddresses[0]
+		}
+
+	case "san_dns":
+		if len(cert.DNSNames) != 0 {
+			return cert.DNSNames[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dosco/graphjin/core"
+)
+
+func TestMTLSHandler(t *testing.T) {
+	ac := &Auth{Type: "mtls"}
+	ac.MTLS.UserID = "san"
+	ac.MTLS.Role = "ou"
+
+	var userID, role interface{}
+
+	h, err := WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = r.Context().Value(core.UserIDKey)
+		role = r.Context().Value(core.UserRoleKey)
+	}), ac, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         "billing",
+			OrganizationalUnit: []string{"service"},
+		},
+		DNSNames: []string{"billing.internal"},
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if userID != "billing.internal" {
+		t.Errorf("expected user id 'billing.internal' got %v", userID)
+	}
+
+	if role != "service" {
+		t.Errorf("expected role 'service' got %v", role)
+	}
+
+	// unverified certificates are ignored
+	req = httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if userID != nil {
+		t.Errorf("expected anonymous user got %v", userID)
+	}
+}
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,11 +15,16 @@ import (
 	"github.com/dosco/graphjin/core"
 	"github.com/dosco/graphjin/internal/serv/internal/rails"
 	"github.com/garyburd/redigo/redis"
+	"github.com/jackc/pgx/v4"
 )
 
-func RailsHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
+func RailsHandler(ac *Auth, db *sql.DB, next http.Handler) (http.HandlerFunc, error) {
 	ru := ac.Rails.URL
 
+	if ac.Rails.SessionTable != "" {
+		return RailsDBHandler(ac, db, next)
+	}
+
 	if strings.HasPrefix(ru, "memcache:") {
 		return RailsMemcacheHandler(ac, next)
 	}
@@ -127,6 +135,58 @@ func RailsMemcacheHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error)
 	}, nil
 }
 
+// RailsDBHandler reads sessions stored in a database table by
+// the activerecord-session_store gem.
+func RailsDBHandler(ac *Auth, db *sql.DB, next http.Handler) (http.HandlerFunc, error) {
+	cookie := ac.Cookie
+
+	if len(cookie) == 0 {
+		return nil, fmt.Errorf("no auth.cookie defined")
+	}
+
+	if db == nil {
+		return nil, fmt.Errorf("auth.rails.session_table: no database connection")
+	}
+
+	// Newer versions of the gem store a hash of the session id (private id)
+	// rather than the id itself so we look for both.
+	sqlStmt := fmt.Sprintf(`SELECT data FROM %s WHERE session_id IN ($1, $2) LIMIT 1`,
+		quoteTable(ac.Rails.SessionTable))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ck, err := r.Cookie(cookie)
+		if err != nil || len(ck.Value) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var sessionData string
+
+		err = db.QueryRowContext(r.Context(), sqlStmt, ck.Value, privateSessionID(ck.Value)).
+			Scan(&sessionData)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, err := rails.ParseSessionData(sessionData)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), core.UserIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}, nil
+}
+
+// privateSessionID returns the id used by Rack to store the session
+// server-side (Rack::Session::SessionId#private_id)
+func privateSessionID(id string) string {
+	h := sha256.Sum256([]byte(id))
+	return "2::" + hex.EncodeToString(h[:])
+}
+
 func RailsCookieHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
 	cookie := ac.Cookie
 	if len(cookie) == 0 {
@@ -188,3 +248,9 @@ func railsAuth(ac *Auth) (*rails.Auth, error) {
 
 	return ra, nil
 }
+
+// quoteTable quotes each part of a table name that may
+// be schema qualified, for example public.sessions
+func quoteTable(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
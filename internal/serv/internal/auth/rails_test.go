@@ -0,0 +1,20 @@
+package auth
+
+import "testing"
+
+func TestQuoteTable(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{"sessions", `"sessions"`},
+		{"public.sessions", `"public"."sessions"`},
+		{`bad"name`, `"bad""name"`},
+	}
+
+	for _, v := range tests {
+		if got := quoteTable(v.name); got != v.exp {
+			t.Errorf("%s: expected %s got %s", v.name, v.exp, got)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dosco/graphjin/core"
+	cache "github.com/go-pkgz/expirable-cache"
+)
+
+type contextkey int
+
+const (
+	varsKey contextkey = iota
+)
+
+const (
+	defaultWebhookTTL     = time.Minute * 5
+	defaultWebhookTimeout = time.Second * 5
+	defaultWebhookMaxKeys = 10000
+)
+
+// hopHeaders are not forwarded to the webhook endpoint
+var hopHeaders = []string{
+	"Connection",
+	"Content-Length",
+	"Content-Type",
+	"Keep-Alive",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type webhookResp struct {
+	UserID         interface{}
+	UserIDProvider string
+	Role           string
+	Roles          []string
+	Vars           map[string]string
+}
+
+// WebhookHandler authenticates requests by forwarding their headers and cookies
+// to an external endpoint which replies with the user ID, role and variables.
+// Replies are cached per token for the configured TTL, a failing webhook
+// fails the request instead of downgrading it to an anonymous user.
+func WebhookHandler(ac *Auth, next http.Handler) (http.HandlerFunc, error) {
+	wh := ac.Webhook
+
+	if wh.URL == "" {
+		return nil, fmt.Errorf("auth '%s': no webhook.url defined", ac.Name)
+	}
+
+	ttl := wh.TTL
+	if ttl == 0 {
+		ttl = defaultWebhookTTL
+	}
+
+	timeout := wh.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	maxKeys := wh.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = defaultWebhookMaxKeys
+	}
+
+	rc, err := cache.NewCache(cache.MaxKeys(maxKeys), cache.TTL(ttl))
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		tok := webhookToken(ac, r)
+		if tok == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var wr *webhookResp
+
+		if v, ok := rc.Get(tok); ok {
+			wr = v.(*webhookResp)
+		} else {
+			var err error
+			if wr, err = callWebhook(client, wh.URL, r); err != nil {
+				http.Error(w, "auth webhook failed", http.StatusBadGateway)
+				return
+			}
+			rc.Set(tok, wr, 0)
+		}
+
+		if wr.UserID == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		ctx = context.WithValue(ctx, core.UserIDKey, wr.UserID)
+
+		if wr.UserIDProvider != "" {
+			ctx = context.WithValue(ctx, core.UserIDProviderKey, wr.UserIDProvider)
+		}
+
+		if len(wr.Roles) != 0 {
+			ctx = context.WithValue(ctx, core.UserRoleKey, wr.Roles)
+		} else if wr.Role != "" {
+			ctx = context.WithValue(ctx, core.UserRoleKey, wr.Role)
+		}
+
+		if len(wr.Vars) != 0 {
+			ctx = context.WithValue(ctx, varsKey, wr.Vars)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}, nil
+}
+
+// webhookToken returns a hash of the credentials of the request, the
+// session cookie and authorization header, it's used as the cache key
+// for the webhook reply.
+func webhookToken(ac *Auth, r *http.Request) string {
+	var ck string
+
+	if ac.Cookie != "" {
+		if c, err := r.Cookie(ac.Cookie); err == nil {
+			ck = c.Value
+		}
+	} else {
+		ck = r.Header.Get("Cookie")
+	}
+
+	ah := r.Header.Get(authHeader)
+
+	if ck == "" && ah == "" {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(ck + "\x00" + ah))
+	return hex.EncodeToString(h[:])
+}
+
+// forwardHeaders returns the request headers sent to the webhook
+func forwardHeaders(r *http.Request) http.Header {
+	hdr := r.Header.Clone()
+	for _, h := range hopHeaders {
+		hdr.Del(h)
+	}
+	return hdr
+}
+
+func callWebhook(client *http.Client, url string, r *http.Request) (*webhookResp, error) {
+	req, err := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = forwardHeaders(r)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// the credentials were rejected, so the user is anonymous
+		return &webhookResp{}, nil
+	default:
+		return nil, fmt.Errorf("auth webhook: unexpected status %d", resp.StatusCode)
+	}
+
+	var v struct {
+		UserID         interface{}            `json:"user_id"`
+		UserIDProvider string                 `json:"user_id_provider"`
+		Role           string                 `json:"role"`
+		Roles          []string               `json:"roles"`
+		Vars           map[string]interface{} `json:"variables"`
+	}
+
+	d := json.NewDecoder(resp.Body)
+	d.UseNumber()
+
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	wr := &webhookResp{
+		UserIDProvider: v.UserIDProvider,
+		Role:           v.Role,
+		Roles:          v.Roles,
+	}
+
+	switch id := v.UserID.(type) {
+	case json.Number:
+		if n, err := strconv.Atoi(string(id)); err == nil {
+			wr.UserID = n
+		} else {
+			wr.UserID = string(id)
+		}
+	case string:
+		if id != "" {
+			wr.UserID = id
+		}
+	}
+
+	if len(v.Vars) != 0 {
+		wr.Vars = make(map[string]string, len(v.Vars))
+
+		for k, val := range v.Vars {
+			switch val1 := val.(type) {
+			case string:
+				wr.Vars[k] = val1
+			case nil:
+				continue
+			default:
+				b, err := json.Marshal(val1)
+				if err != nil {
+					return nil, err
+				}
+				wr.Vars[k] = string(b)
+			}
+		}
+	}
+
+	return wr, nil
+}
+
+// Vars returns the variables set by the auth webhook for the request
+func Vars(ct context.Context) map[string]string {
+	if v, ok := ct.Value(varsKey).(map[string]string); ok {
+		return v
+	}
+	return nil
+}
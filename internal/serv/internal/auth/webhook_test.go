@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dosco/graphjin/core"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		ck, err := r.Cookie("_session")
+		if err == nil && ck.Value == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err != nil || ck.Value != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		//nolint: errcheck
+		w.Write([]byte(`{ "user_id": 5, "role": "admin", "variables": { "org_id": 10, "plan": "pro" } }`))
+	}))
+	defer ts.Close()
+
+	ac := &Auth{Type: "webhook"}
+	ac.Webhook.URL = ts.URL
+	ac.Webhook.TTL = time.Minute
+
+	var userID, role interface{}
+	var vars map[string]string
+
+	h, err := WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = r.Context().Value(core.UserIDKey)
+		role = r.Context().Value(core.UserRoleKey)
+		vars = Vars(r.Context())
+	}), ac, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+		req.AddCookie(&http.Cookie{Name: "_session", Value: "abc"})
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if userID != 5 {
+			t.Errorf("expected user id 5 got %v", userID)
+		}
+
+		if role != "admin" {
+			t.Errorf("expected role 'admin' got %v", role)
+		}
+
+		if vars["org_id"] != "10" || vars["plan"] != "pro" {
+			t.Errorf("unexpected variables %v", vars)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected webhook to be called once got %d", calls)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	req.AddCookie(&http.Cookie{Name: "_session", Value: "xyz"})
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if userID != nil {
+		t.Errorf("expected anonymous user got %v", userID)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if calls != 2 {
+		t.Errorf("expected no webhook call without credentials")
+	}
+
+	// only the credentials are the cache key
+	req = httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	req.AddCookie(&http.Cookie{Name: "_session", Value: "abc"})
+	req.Header.Set("X-Request-Id", "2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if calls != 2 {
+		t.Errorf("expected cached reply for other headers got %d calls", calls)
+	}
+
+	// server errors fail the request and are not cached
+	for i := 0; i < 2; i++ {
+		userID = nil
+		req = httptest.NewRequest("POST", "/api/v1/graphql", nil)
+		req.AddCookie(&http.Cookie{Name: "_session", Value: "fail"})
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("expected status %d got %d", http.StatusBadGateway, w.Code)
+		}
+		if userID != nil {
+			t.Errorf("expected request to fail got user id %v", userID)
+		}
+	}
+
+	if calls != 4 {
+		t.Errorf("expected failed webhook replies not to be cached got %d calls", calls)
+	}
+}
+
+func TestWebhookCacheEviction(t *testing.T) {
+	var calls int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		//nolint: errcheck
+		w.Write([]byte(`{ "user_id": 5 }`))
+	}))
+	defer ts.Close()
+
+	ac := &Auth{Type: "webhook"}
+	ac.Webhook.URL = ts.URL
+	ac.Webhook.MaxKeys = 1
+
+	h, err := WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), ac, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(tok string) {
+		req := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	call("a")
+	call("a")
+
+	if calls != 1 {
+		t.Fatalf("expected a cached reply got %d calls", calls)
+	}
+
+	// the oldest token is evicted once the cache is full
+	call("b")
+	call("a")
+
+	if calls != 3 {
+		t.Errorf("expected the first token to be evicted got %d calls", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package rails
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -95,6 +96,31 @@ func ParseCookie(cookie string) (string, error) {
 	return getUserId([]byte(cookie))
 }
 
+// ParseSessionData decodes the 'data' column of a session row stored
+// by the activerecord-session_store gem. Depending on the serializer
+// used the data is either JSON or a base64 encoded marshalled hash.
+func ParseSessionData(data string) (string, error) {
+	data = strings.TrimSpace(data)
+
+	if data == "" {
+		return "", errSessionData
+	}
+
+	if data[0] == '{' {
+		return getUserId([]byte(data))
+	}
+
+	// Ruby's Base64.encode64 wraps lines at 60 characters
+	data = strings.NewReplacer("\n", "", "\r", "").Replace(data)
+
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	return ParseCookie(string(b))
+}
+
 func getUserId(data []byte) (userID string, err error) {
 	var sessionData map[string]interface{}
 
@@ -77,3 +77,24 @@ func TestRailsMarshaledSession(t *testing.T) {
 		t.Errorf("Expecting userID 2 got %s", userID)
 	}
 }
+
+func TestRailsActiveRecordSession(t *testing.T) {
+	sessionData := []string{
+		// marshal serializer (base64 encoded)
+		"BAh7CEkiFW1lbWJlcl9yZXR1cm5fdG8GOgZFVEkiBi8GOwBUSSIZd2FyZGVuLnVzZXIudXNlci5r\nZXkGOwBUWwdbBmkHSSIiJDJhJDExJDZTZ1hkdk85aGxkODJrUUF2cEVZM2UGOwBUSSIQX2NzcmZf\ndG9rZW4GOwBGSSIxN2xxd2oxVXNUVGdiWEJRS0g0aXBDTlczMnVMdXN2ZlNQZHMxdHhwcE1lYz0G\nOwBG\n",
+		// json serializer
+		`{"warden.user.user.key":[[2],"secret"]}`,
+	}
+
+	for _, v := range sessionData {
+		userID, err := ParseSessionData(v)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if userID != "2" {
+			t.Errorf("Expecting userID 2 got %s", userID)
+		}
+	}
+}
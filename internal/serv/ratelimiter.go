@@ -0,0 +1,479 @@
+package serv
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dosco/graphjin/core"
+	cache "github.com/go-pkgz/expirable-cache"
+)
+
+type rateLimiterKey struct{}
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	defaultIdleTimeout  = 5 * time.Minute
+	defaultMaxKeys      = 10000
+	defaultRoleCacheTTL = time.Minute
+)
+
+// limiter is a token bucket that is refilled at 'rate' tokens per second
+// upto 'burst' tokens. Requests are allowed as long as there is at least
+// one token left and are then charged their full cost which can leave
+// the bucket in debt.
+type limiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int, now time.Time) *limiter {
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+func (l *limiter) refill(now time.Time) {
+	if d := now.Sub(l.last); d > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+d.Seconds()*l.rate)
+		l.last = now
+	}
+}
+
+// allow takes a single token from the bucket if there is one left
+func (l *limiter) allow(now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(now)
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// charge takes n tokens from the bucket, a single charge is capped
+// at the bucket size
+func (l *limiter) charge(now time.Time, n int) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(now)
+	l.tokens -= math.Min(float64(n), l.burst)
+}
+
+// state returns the tokens remaining, the seconds till the bucket is
+// full again and the seconds till the next request is allowed
+func (l *limiter) state(now time.Time) (remaining, reset, retry int) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(now)
+
+	if l.tokens > 0 {
+		remaining = int(l.tokens)
+	}
+	reset = int(math.Ceil((l.burst - l.tokens) / l.rate))
+
+	if l.tokens < 1 {
+		retry = int(math.Ceil((1 - l.tokens) / l.rate))
+	}
+	return
+}
+
+func (l *limiter) setHeaders(w http.ResponseWriter, now time.Time) {
+	remaining, reset, retry := l.state(now)
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(int(l.burst)))
+	h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(reset))
+
+	if retry != 0 {
+		h.Set("Retry-After", strconv.Itoa(retry))
+	}
+}
+
+// limiterStore holds a limiter per key, limiters not used for the
+// idle timeout are evicted and once there are more than max keys the
+// least recently used one is evicted
+type limiterStore struct {
+	sync.Mutex
+	idle    time.Duration
+	maxKeys int
+	sweep   time.Time
+	m       map[string]*list.Element
+	lru     *list.List
+}
+
+type limiterEntry struct {
+	key string
+	l   *limiter
+}
+
+func newLimiterStore(idle time.Duration, maxKeys int) *limiterStore {
+	return &limiterStore{
+		idle:    idle,
+		maxKeys: maxKeys,
+		m:       make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (ls *limiterStore) get(key string, rate float64, burst int, now time.Time) *limiter {
+	ls.Lock()
+	defer ls.Unlock()
+
+	if now.Sub(ls.sweep) > ls.idle {
+		ls.evict(now)
+		ls.sweep = now
+	}
+
+	if e, ok := ls.m[key]; ok {
+		ls.lru.MoveToFront(e)
+		return e.Value.(*limiterEntry).l
+	}
+
+	l := newLimiter(rate, burst, now)
+	ls.m[key] = ls.lru.PushFront(&limiterEntry{key: key, l: l})
+
+	if ls.maxKeys > 0 && ls.lru.Len() > ls.maxKeys {
+		ls.remove(ls.lru.Back())
+	}
+	return l
+}
+
+func (ls *limiterStore) evict(now time.Time) {
+	for e := ls.lru.Back(); e != nil; {
+		prev := e.Prev()
+		le := e.Value.(*limiterEntry)
+
+		le.l.Lock()
+		idle := now.Sub(le.l.last) > ls.idle
+		le.l.Unlock()
+
+		if idle {
+			ls.remove(e)
+		}
+		e = prev
+	}
+}
+
+func (ls *limiterStore) remove(e *list.Element) {
+	ls.lru.Remove(e)
+	delete(ls.m, e.Value.(*limiterEntry).key)
+}
+
+// rateLimit limits requests by client ip before auth so the auth backends
+// are throttled too and then by the configured key and role after auth
+type rateLimit struct {
+	conf   *Config
+	rk     *rateLimitKeys
+	roles  map[string]RoleRateLimit
+	ls     *limiterStore
+	ips    *limiterStore
+	rc     cache.Cache
+	rate   float64
+	bucket int
+}
+
+func newRateLimit(sc *ServConfig) *rateLimit {
+	conf := &sc.conf.RateLimiter
+
+	switch conf.Key {
+	case "", "ip", "user", "role":
+	case "api_key":
+		if len(conf.APIKeys) == 0 {
+			sc.log.Fatalf("ERR rate limiter: no api_keys defined")
+		}
+	default:
+		sc.log.Fatalf("ERR rate limiter: invalid key: %s", conf.Key)
+	}
+
+	rk, err := newRateLimitKeys(sc)
+	if err != nil {
+		sc.log.Fatalf("ERR rate limiter: %s", err)
+	}
+
+	idle := conf.IdleTimeout
+	if idle == 0 {
+		idle = defaultIdleTimeout
+	}
+
+	maxKeys := conf.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = defaultMaxKeys
+	}
+
+	rc, err := cache.NewCache(cache.MaxKeys(maxKeys), cache.TTL(defaultRoleCacheTTL))
+	if err != nil {
+		sc.log.Fatalf("ERR rate limiter: %s", err)
+	}
+
+	rl := &rateLimit{
+		conf:   sc.conf,
+		rk:     rk,
+		roles:  make(map[string]RoleRateLimit, len(conf.Roles)),
+		ls:     newLimiterStore(idle, maxKeys),
+		ips:    newLimiterStore(idle, maxKeys),
+		rc:     rc,
+		rate:   conf.Rate,
+		bucket: conf.Bucket,
+	}
+
+	// the client ip limit comes before the role is known so it
+	// must allow as many requests as the highest role limit
+	for _, v := range conf.Roles {
+		rl.roles[strings.ToLower(v.Name)] = v
+
+		if v.Rate > rl.rate {
+			rl.rate = v.Rate
+		}
+		if v.Bucket > rl.bucket {
+			rl.bucket = v.Bucket
+		}
+	}
+
+	return rl
+}
+
+// clientLimiter limits requests by client ip, it's added before auth
+// so webhook calls and session lookups are limited as well
+func (rl *rateLimit) clientLimiter(h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		l := rl.ips.get("ip:"+rl.rk.clientIP(r), rl.rate, rl.bucket, now)
+
+		if !l.allow(now) {
+			l.setHeaders(w, now)
+			http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// limiter limits requests by the configured key and the role
+// of the request, it's added after auth
+func (rl *rateLimit) limiter(h http.Handler) http.Handler {
+	conf := &rl.conf.RateLimiter
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		ct := r.Context()
+
+		rate, burst := conf.Rate, conf.Bucket
+		role := rl.requestRole(ct)
+
+		if v, ok := rl.roles[role]; ok && v.Rate > 0 && v.Bucket > 0 {
+			rate, burst = v.Rate, v.Bucket
+		}
+
+		l := rl.ls.get(rl.rk.key(r, role), rate, burst, now)
+
+		if !l.allow(now) {
+			l.setHeaders(w, now)
+			http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		l.setHeaders(w, now)
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(ct, rateLimiterKey{}, l)))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// chargeRateLimit charges the request the remainder of its cost
+// and updates the rate limit headers
+func chargeRateLimit(ct context.Context, w http.ResponseWriter, cost int) {
+	l, ok := ct.Value(rateLimiterKey{}).(*limiter)
+	if !ok {
+		return
+	}
+	now := time.Now()
+
+	// the first token was taken when the request was allowed
+	if cost > 1 {
+		l.charge(now, cost-1)
+	}
+	l.setHeaders(w, now)
+}
+
+// rateLimitKeys finds the key used to limit a request
+type rateLimitKeys struct {
+	conf    *Config
+	apiKeys map[string]struct{}
+	proxies []*net.IPNet
+}
+
+func newRateLimitKeys(sc *ServConfig) (*rateLimitKeys, error) {
+	conf := &sc.conf.RateLimiter
+	rk := &rateLimitKeys{conf: sc.conf, apiKeys: make(map[string]struct{})}
+
+	for _, v := range conf.APIKeys {
+		rk.apiKeys[v] = struct{}{}
+	}
+
+	for _, v := range conf.TrustedProxies {
+		if !strings.Contains(v, "/") {
+			if strings.Contains(v, ":") {
+				v += "/128"
+			} else {
+				v += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+		rk.proxies = append(rk.proxies, n)
+	}
+
+	return rk, nil
+}
+
+// key returns the key used to find the limiter for the request, it
+// includes the role since each role can have its own rate and bucket.
+func (rk *rateLimitKeys) key(r *http.Request, role string) string {
+	if rk.conf.RateLimiter.Key == "role" {
+		return "role:" + role
+	}
+	return rk.clientKey(r) + "/" + role
+}
+
+// clientKey returns the key of the client making the request. The user
+// falls back to the client ip when not set and api keys that are not one
+// of the configured keys fall back to the user or client ip.
+func (rk *rateLimitKeys) clientKey(r *http.Request) string {
+	conf := &rk.conf.RateLimiter
+
+	switch conf.Key {
+	case "user":
+		if v := r.Context().Value(core.UserIDKey); v != nil {
+			return "user:" + fmt.Sprint(v)
+		}
+
+	case "api_key":
+		hdr := conf.APIKeyHeader
+		if hdr == "" {
+			hdr = defaultAPIKeyHeader
+		}
+		if v := r.Header.Get(hdr); v != "" {
+			if _, ok := rk.apiKeys[v]; ok {
+				return "key:" + v
+			}
+		}
+		if v := r.Context().Value(core.UserIDKey); v != nil {
+			return "user:" + fmt.Sprint(v)
+		}
+	}
+
+	return "ip:" + rk.clientIP(r)
+}
+
+// clientIP returns the ip of the client, the ip header is only
+// used for requests coming from a trusted proxy
+func (rk *rateLimitKeys) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !rk.trusted(ip) {
+		return ip
+	}
+
+	hdr := rk.conf.RateLimiter.IPHeader
+	if hdr == "" {
+		hdr = "X-Remote-Address"
+	}
+
+	// the last address not added by a trusted proxy is the client
+	list := strings.Split(r.Header.Get(hdr), ",")
+
+	for i := len(list) - 1; i >= 0; i-- {
+		v := strings.TrimSpace(list[i])
+		if v == "" {
+			break
+		}
+		ip = v
+		if !rk.trusted(v) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (rk *rateLimitKeys) trusted(ip string) bool {
+	v := net.ParseIP(ip)
+	if v == nil {
+		return false
+	}
+	for _, n := range rk.proxies {
+		if n.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestRole returns the role the request is run with, it's resolved
+// the same way as in the query so the active role and roles query are
+// taken into account. The roles of users are cached for a short while
+// so the roles query is not run on every request. Requests with an
+// invalid role fail in the query so the default limit is used for them.
+func (rl *rateLimit) requestRole(ct context.Context) string {
+	if gj == nil {
+		return ""
+	}
+
+	uid := ct.Value(core.UserIDKey)
+	if uid == nil {
+		return requestRole(ct)
+	}
+
+	k := fmt.Sprintf("%v\x00%v\x00%v", uid,
+		ct.Value(core.UserRoleKey), ct.Value(core.ActiveRoleKey))
+
+	if v, ok := rl.rc.Get(k); ok {
+		return v.(string)
+	}
+
+	role, err := gj.RequestRole(ct)
+	if err != nil {
+		return ""
+	}
+	role = strings.ToLower(role)
+	rl.rc.Set(k, role, 0)
+
+	return role
+}
+
+// requestRole returns the role of requests without a user, it does
+// not need the database
+func requestRole(ct context.Context) string {
+	role, err := gj.RequestRole(ct)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(role)
+}
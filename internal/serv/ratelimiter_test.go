@@ -0,0 +1,174 @@
+package serv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dosco/graphjin/core"
+)
+
+func TestLimiterCost(t *testing.T) {
+	now := time.Now()
+	l := newLimiter(1, 5, now)
+
+	if !l.allow(now) {
+		t.Fatal("expected request to be allowed")
+	}
+	l.charge(now, 9)
+
+	if rem, _, retry := l.state(now); rem != 0 || retry != 2 {
+		t.Fatalf("expected 0 remaining and retry of 2s got %d, %d", rem, retry)
+	}
+
+	if l.allow(now.Add(time.Second)) {
+		t.Fatal("expected request to be limited")
+	}
+
+	if !l.allow(now.Add(2 * time.Second)) {
+		t.Fatal("expected request to be allowed")
+	}
+}
+
+func TestLimiterEviction(t *testing.T) {
+	now := time.Now()
+	ls := newLimiterStore(time.Minute, 2)
+
+	l := ls.get("ip:1", 1, 1, now)
+	l.allow(now)
+
+	if l1 := ls.get("ip:1", 1, 1, now.Add(30*time.Second)); l1 != l {
+		t.Fatal("expected the same limiter")
+	}
+
+	ls.get("ip:2", 1, 1, now.Add(2*time.Minute))
+
+	if _, ok := ls.m["ip:1"]; ok {
+		t.Fatal("expected idle limiter to be evicted")
+	}
+
+	// the least recently used limiter is evicted over max keys
+	ls.get("ip:3", 1, 1, now.Add(2*time.Minute))
+	ls.get("ip:2", 1, 1, now.Add(2*time.Minute))
+	ls.get("ip:4", 1, 1, now.Add(2*time.Minute))
+
+	if _, ok := ls.m["ip:3"]; ok {
+		t.Fatal("expected least recently used limiter to be evicted")
+	}
+
+	if len(ls.m) != 2 || ls.lru.Len() != 2 {
+		t.Fatalf("expected 2 limiters got %d", len(ls.m))
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	sc := &ServConfig{conf: &Config{}}
+	sc.conf.RateLimiter.Key = "user"
+	sc.conf.RateLimiter.APIKeys = []string{"abc"}
+
+	rk, err := newRateLimitKeys(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	if k := rk.key(r, "anon"); k != "ip:10.0.0.1/anon" {
+		t.Fatalf("expected ip key got %s", k)
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), core.UserIDKey, 5))
+
+	if k := rk.key(r, "user"); k != "user:5/user" {
+		t.Fatalf("expected user key got %s", k)
+	}
+
+	// each role of the user has its own limiter
+	if k := rk.key(r, "admin"); k != "user:5/admin" {
+		t.Fatalf("expected user key with role got %s", k)
+	}
+
+	sc.conf.RateLimiter.Key = "api_key"
+	r.Header.Set("X-API-Key", "abc")
+
+	if k := rk.key(r, "user"); k != "key:abc/user" {
+		t.Fatalf("expected api key got %s", k)
+	}
+
+	// unknown api keys fall back to the user
+	r.Header.Set("X-API-Key", "random")
+
+	if k := rk.key(r, "user"); k != "user:5/user" {
+		t.Fatalf("expected user key got %s", k)
+	}
+
+	sc.conf.RateLimiter.Key = "role"
+
+	if k := rk.key(r, "admin"); k != "role:admin" {
+		t.Fatalf("expected role key got %s", k)
+	}
+}
+
+func TestClientLimiter(t *testing.T) {
+	sc := &ServConfig{conf: &Config{}}
+	sc.conf.RateLimiter.Rate = 1
+	sc.conf.RateLimiter.Bucket = 1
+	sc.conf.RateLimiter.Key = "user"
+	sc.conf.RateLimiter.Roles = []RoleRateLimit{{Name: "admin", Rate: 1, Bucket: 2}}
+
+	rl := newRateLimit(sc)
+
+	// stands in for the auth backend
+	calls := 0
+	h := rl.clientLimiter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// the client limit uses the highest role bucket
+	if calls != 2 {
+		t.Fatalf("expected auth to be called 2 times got %d", calls)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	sc := &ServConfig{conf: &Config{}}
+	sc.conf.RateLimiter.IPHeader = "X-Forwarded-For"
+	sc.conf.RateLimiter.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1"}
+
+	rk, err := newRateLimitKeys(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remote string
+		header string
+		ip     string
+	}{
+		{"1.2.3.4:1000", "5.6.7.8", "1.2.3.4"},
+		{"10.0.0.1:1000", "5.6.7.8", "5.6.7.8"},
+		{"10.0.0.1:1000", "9.9.9.9, 5.6.7.8, 192.168.1.1", "5.6.7.8"},
+		{"192.168.1.1:1000", "", "192.168.1.1"},
+	}
+
+	for _, v := range tests {
+		r := httptest.NewRequest("POST", "/api/v1/graphql", nil)
+		r.RemoteAddr = v.remote
+		if v.header != "" {
+			r.Header.Set("X-Forwarded-For", v.header)
+		}
+
+		if ip := rk.clientIP(r); ip != v.ip {
+			t.Errorf("%s (%s): expected %s got %s", v.remote, v.header, v.ip, ip)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package serv
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
@@ -78,9 +81,24 @@ func startHTTP(sc *ServConfig) {
 		Handler:        routes,
 		ReadTimeout:    5 * time.Second,
 		WriteTimeout:   10 * time.Second,
+		IdleTimeout:    sc.conf.IdleTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
 
+	if sc.conf.ReadTimeout != 0 {
+		srv.ReadTimeout = sc.conf.ReadTimeout
+	}
+
+	if sc.conf.WriteTimeout != 0 {
+		srv.WriteTimeout = sc.conf.WriteTimeout
+	}
+
+	if sc.conf.tlsEnabled() {
+		if srv.TLSConfig, err = tlsConfig(sc); err != nil {
+			sc.log.Fatalf("ERR %s", err)
+		}
+	}
+
 	if sc.conf.telemetryEnabled() {
 		srv.Handler = &ochttp.Handler{Handler: routes}
 	}
@@ -108,13 +126,52 @@ func startHTTP(sc *ServConfig) {
 	sc.log.Printf("INF GraphJin started, version: %s, git-branch: %s, host-port: %s, app-name: %s, env: %s\n",
 		version, gitBranch, sc.conf.hostPort, appName, env)
 
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	if sc.conf.tlsEnabled() {
+		err = srv.ListenAndServeTLS(
+			sc.conf.relPath(sc.conf.TLS.CertFile),
+			sc.conf.relPath(sc.conf.TLS.KeyFile))
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	if err != http.ErrServerClosed {
 		sc.log.Fatalln("INF server closed")
 	}
 
 	<-idleConnsClosed
 }
 
+func tlsConfig(sc *ServConfig) (*tls.Config, error) {
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if sc.conf.TLS.ClientCAFile == "" {
+		return tc, nil
+	}
+
+	b, err := ioutil.ReadFile(sc.conf.relPath(sc.conf.TLS.ClientCAFile))
+	if err != nil {
+		return nil, err
+	}
+
+	tc.ClientCAs = x509.NewCertPool()
+
+	if !tc.ClientCAs.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("tls: no certificates found in client_ca_file: %s",
+			sc.conf.TLS.ClientCAFile)
+	}
+
+	switch sc.conf.TLS.ClientAuth {
+	case "", "require":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("tls: invalid client_auth value: %s", sc.conf.TLS.ClientAuth)
+	}
+
+	return tc, nil
+}
+
 func routeHandler(sc *ServConfig) (http.Handler, error) {
 	var err error
 	mux := http.NewServeMux()
@@ -130,11 +187,6 @@ func routeHandler(sc *ServConfig) (http.Handler, error) {
 	// Main GraphQL API handler
 	apiHandler := apiV1Handler(sc)
 
-	// API rate limiter
-	if sc.conf.rateLimiterEnable() {
-		apiHandler = rateLimiter(sc, apiHandler)
-	}
-
 	routes := map[string]http.Handler{
 		"/health": http.HandlerFunc(health(sc)),
 		apiRoute:  apiHandler,
@@ -191,7 +243,7 @@ func setActionRoutes(sc *ServConfig, routes map[string]http.Handler) error {
 		p := fmt.Sprintf("/api/v1/actions/%s", strings.ToLower(a.Name))
 
 		if ac := findAuth(sc, a.AuthName); ac != nil {
-			routes[p], err = auth.WithAuth(fn, ac)
+			routes[p], err = auth.WithAuth(fn, ac, sc.db)
 		} else {
 			routes[p] = fn
 		}
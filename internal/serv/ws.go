@@ -109,7 +109,7 @@ func apiV1Ws(servConf *ServConfig, w http.ResponseWriter, r *http.Request) {
 				err = conn.WritePreparedMessage(initMsg)
 			}
 
-			handler, _ := auth.WithAuth(http.HandlerFunc(hfn), &servConf.conf.Auth)
+			handler, _ := auth.WithAuth(http.HandlerFunc(hfn), &servConf.conf.Auth, servConf.db)
 
 			if err != nil {
 				break
@@ -129,7 +129,8 @@ func apiV1Ws(servConf *ServConfig, w http.ResponseWriter, r *http.Request) {
 			if run {
 				continue
 			}
-			m, err = gj.Subscribe(ctx, msg.Payload.Query, msg.Payload.Vars)
+			rc := reqConfig(servConf, ctx, r)
+			m, err = gj.SubscribeEx(ctx, msg.Payload.Query, msg.Payload.Vars, &rc)
 			if err == nil {
 				go waitForData(servConf, done, conn, m)
 				run = true